@@ -8,10 +8,12 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,10 +21,15 @@ import (
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/hulklab/yago/libs/tracelib"
 	"github.com/mitchellh/mapstructure"
 	"github.com/robfig/cron"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -35,6 +42,16 @@ func AddAppInitHook(hs ...AppInitHook) {
 	appInitHooks = append(appInitHooks, hs...)
 }
 
+// 只注册路由的 hook, 在 AppInitHook 之后执行, 不能有连接外部服务, 修改 http 引擎等副作用
+// openapi 命令只执行这类 hook, 在 AppInitHook 中注册的路由不会出现在导出的文档中
+type RouterInitHook func() error
+
+var routerInitHooks = make([]RouterInitHook, 0)
+
+func AddRouterInitHook(hs ...RouterInitHook) {
+	routerInitHooks = append(routerInitHooks, hs...)
+}
+
 type App struct {
 	// 是否开启debug模式
 	DebugMode bool
@@ -48,9 +65,6 @@ type App struct {
 	HttpEnable bool
 	// http路由配置
 	HttpRouters []*HttpRouter
-	// http close chan
-	httpCloseChan     chan int
-	httpCloseDoneChan chan int
 	// https 证书配置
 	HttpSslOn    bool
 	HttpCertFile string
@@ -72,25 +86,44 @@ type App struct {
 	HttpGzipLevel int
 	// http pprof
 	HttpPprof bool
+	// http healthz, readyz
+	HttpHealthOn bool
+	// http openapi 文档路由
+	HttpOpenAPIOn bool
+	// http 响应渲染, 按 Accept 协商, 第一个为默认
+	HttpRenderers []Renderer
+	// yago.Err 错误码映射为 http status, 默认使用登记错误码时的 http status
+	HttpErrStatusOn bool
+	// SetHttpErrStatus 和 app.http_err_status 设置的映射, 覆盖登记的 http status
+	HttpErrStatus map[int]int
 
 	// 开启task服务
 	TaskEnable bool
 	// task路由配置
 	TaskRouters []*TaskRouter
-	// task close chan
-	taskCloseChan     chan int
-	taskCloseDoneChan chan int
+	taskCron    *cron.Cron
+	taskWg      sync.WaitGroup
 
 	// rpc
 	RpcEnable bool
 	rpcEngine *grpc.Server
-
-	// rpc close chan
-	rpcCloseChan     chan int
-	rpcCloseDoneChan chan int
+	// grpc.health.v1 服务, 关闭开始时置为 NOT_SERVING
+	rpcHealthServer *health.Server
+	// rpc 与 http 共用 http_addr 端口, 按 content-type 分发
+	RpcHttpMux bool
+	// mux 模式下正在处理的 rpc 请求数
+	rpcMuxActive int64
+
+	// 按添加顺序启动, 逆序关闭
+	servers []*serverEntry
+	// app 开始关闭时 cancel
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// com close chan
 	comCloseDoneChan chan int
+	// app 开始关闭时置为 1, readiness 检查失败
+	closing int32
 }
 
 var (
@@ -109,6 +142,9 @@ func NewApp() *App {
 
 	app.DebugMode = Config.GetBool("app.debug")
 
+	// 语言包
+	initI18n()
+
 	// init http
 	app.HttpEnable = Config.GetBool("app.http_enable")
 	if app.HttpEnable {
@@ -122,11 +158,9 @@ func NewApp() *App {
 		// use logger
 		if app.DebugMode {
 			app.httpEngine.Use(gin.Logger())
-		} else {
-			app.httpEngine.Use(gin.Recovery())
 		}
-		app.httpCloseChan = make(chan int, 1)
-		app.httpCloseDoneChan = make(chan int, 1)
+		// panic 时返回 ErrSystem
+		app.httpEngine.Use(httpRecoveryMiddleware)
 
 		app.HttpViewRender = Config.GetBool("app.http_view_render")
 		if app.HttpViewRender {
@@ -204,6 +238,12 @@ func NewApp() *App {
 		}
 
 		app.HttpPprof = Config.GetBool("app.http_pprof_on")
+
+		app.HttpHealthOn = Config.GetBool("app.http_health_on")
+
+		app.HttpOpenAPIOn = Config.GetBool("app.http_openapi_on")
+
+		app.initHttpRender()
 	}
 
 	hasHttp := Config.IsSet("app.http_addr")
@@ -219,17 +259,14 @@ func NewApp() *App {
 	// init rpc
 	app.RpcEnable = Config.GetBool("app.rpc_enable")
 	if app.RpcEnable {
-		app.rpcCloseChan = make(chan int, 1)
-		app.rpcCloseDoneChan = make(chan int, 1)
+		app.RpcHttpMux = Config.GetBool("app.rpc_http_mux_on")
+		app.rpcHealthServer = health.NewServer()
 	}
 
 	// init task
 	app.TaskEnable = Config.GetBool("app.task_enable")
-	if app.TaskEnable {
-		app.taskCloseChan = make(chan int, 1)
-		app.taskCloseDoneChan = make(chan int, 1)
-	}
 
+	app.ctx, app.cancel = context.WithCancel(context.Background())
 	app.comCloseDoneChan = make(chan int, 1)
 
 	return app
@@ -244,7 +281,8 @@ func init() {
 
 	log.SetFlags(log.LstdFlags)
 
-	initGrpcServer()
+	// deprecated, for generated code registering services to *grpc.Server
+	RpcServer = newGrpcServer()
 }
 
 func (a *App) HttpEngine() *gin.Engine {
@@ -260,29 +298,30 @@ func (a *App) HTTPSServer() *http.Server {
 }
 
 func (a *App) Run() {
-	if len(appInitHooks) > 0 {
-		for _, f := range appInitHooks {
-			err := f(a)
-			if err != nil {
-				fatalln("init err:", err.Error())
-			}
-		}
+	a.runInitHooks()
+
+	if a.RpcEnable {
+		// rpc server 需要在 http 之前准备好
+		a.loadRpcServer()
 	}
 
+	// interceptors must be ready before serve, rpc gateway of http uses them too
+	a.loadRpcInterceptor()
+
+	// 内置服务先于自定义服务启动: task -> rpc -> http
+	builtinServers := make([]*serverEntry, 0, 3)
 	if a.TaskEnable {
-		// 开启 task
-		go a.runTask()
+		builtinServers = append(builtinServers, newServerEntry("Task", &builtinServer{a.startTask, a.stopTask}, WithStopTimeout(getStopTimeWait("app.task_stop_time_wait"))))
 	}
-
 	if a.RpcEnable {
-		// 开启 rpc
-		go a.runRpc()
+		builtinServers = append(builtinServers, newServerEntry("Rpc", &builtinServer{a.startRpc, a.stopRpc}, WithStopTimeout(getStopTimeWait("app.rpc_stop_time_wait"))))
 	}
-
 	if a.HttpEnable {
-		// 开启 http
-		go a.runHttp()
+		builtinServers = append(builtinServers, newServerEntry("Http", &builtinServer{a.startHttp, a.stopHttp}, WithStopTimeout(getStopTimeWait("app.http_stop_time_wait"))))
 	}
+	a.servers = append(builtinServers, a.servers...)
+
+	a.startServers()
 
 	// 生成 pid
 	a.genPid()
@@ -290,6 +329,30 @@ func (a *App) Run() {
 	a.startSignal()
 }
 
+// 默认中间件等在 AppInitHook 中注册, rpc 网关等路由在 RouterInitHook 中注册
+func (a *App) runInitHooks() {
+	// 应用可能在 init 中替换了内置错误变量, 此时再登记
+	LoadErrCodes()
+
+	for _, f := range appInitHooks {
+		err := f(a)
+		if err != nil {
+			fatalln("init err:", err.Error())
+		}
+	}
+
+	runRouterInitHooks()
+}
+
+func runRouterInitHooks() {
+	for _, f := range routerInitHooks {
+		err := f()
+		if err != nil {
+			fatalln("init router err:", err.Error())
+		}
+	}
+}
+
 func (a *App) genPid() {
 	pidFile, ok := getPidFile()
 	if !ok {
@@ -329,8 +392,9 @@ func (a *App) registerHttpRouter(g *HttpGroupRouter) {
 			})
 		}
 
-		name := runtime.FuncForPC(reflect.ValueOf(actions[len(actions)-1]).Pointer()).Name()
-		debugf("[HTTP] %-6s %-25s --> %s\n", method, r.Url(), strings.NewReplacer("(", "", ")", "", "*", "").Replace(name))
+		httpRouterIndex[r.Method+" "+joinHttpPath(g.GinGroup.BasePath(), r.Path)] = r
+
+		debugf("[HTTP] %-6s %-25s --> %s\n", method, r.Url(), strings.NewReplacer("(", "", ")", "", "*", "").Replace(r.handlerName))
 
 		switch method {
 		case http.MethodGet:
@@ -353,6 +417,19 @@ func (a *App) registerHttpRouter(g *HttpGroupRouter) {
 	}
 }
 
+// same as gin joinPaths, keep the trailing slash of relative path
+func joinHttpPath(absolutePath, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+
+	finalPath := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
+
 func (a *App) registerHttpGroupRouter(group map[string]*HttpGroupRouter) {
 	for _, g := range group {
 		if g.Parent == nil {
@@ -382,7 +459,15 @@ func (a *App) registerHttpGroupRouter(group map[string]*HttpGroupRouter) {
 }
 
 func (a *App) loadHttpRouter() error {
-	if len(httpGroupRouterMap) == 0 {
+	a.loadHttpRender()
+
+	// health and metrics routes are registered before cors and global middleware
+	a.loadHealthRouter()
+	a.loadOpenAPIRouter()
+	a.loadMetricsRouter()
+	a.loadTraceMiddleware()
+
+	if len(httpGroupRouterMap) == 0 && !a.HttpHealthOn && !a.HttpOpenAPIOn && !MetricsEnabled() {
 		return errHttpRouteEmpty
 	}
 
@@ -420,8 +505,11 @@ func (a *App) loadHttpRouter() error {
 		})
 	}
 
-	// register global middleware
-	for _, m := range httpGlobalMiddleware {
+	// register default and global middleware
+	middlewares := make(HttpGlobalMiddleware, 0, len(httpDefaultMiddleware)+len(httpGlobalMiddleware))
+	middlewares = append(middlewares, httpDefaultMiddleware...)
+	middlewares = append(middlewares, httpGlobalMiddleware...)
+	for _, m := range middlewares {
 		handler := m
 		a.httpEngine.Use(func(c *gin.Context) {
 			ctx, err := getCtxFromGin(c)
@@ -438,24 +526,57 @@ func (a *App) loadHttpRouter() error {
 	return nil
 }
 
-func (a *App) runHttp() {
-	// load router
-	if err := a.loadHttpRouter(); err != nil {
-		a.httpCloseDoneChan <- 1
-		return
+func (a *App) getHttpHandler(server *http.Server) http.Handler {
+	if !a.RpcHttpMux {
+		return a.httpEngine
 	}
 
-	if a.HttpSslOn && !Config.IsSet("app.https_addr") {
-		fatalln("https_addr is required when http_ssl_on is true")
+	// h2c connections are hijacked, configure http2 then they can receive GOAWAY when server shutdown
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(server, h2s); err != nil {
+		fatalln("configure http2 err:", err.Error())
 	}
 
-	hasHttp := Config.IsSet("app.http_addr")
-	hasHttps := Config.IsSet("app.https_addr")
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			atomic.AddInt64(&a.rpcMuxActive, 1)
+			defer atomic.AddInt64(&a.rpcMuxActive, -1)
 
-	if hasHttp {
+			a.rpcEngine.ServeHTTP(w, r)
+			return
+		}
+
+		a.httpEngine.ServeHTTP(w, r)
+	}), h2s)
+}
+
+func (a *App) waitRpcMuxRequests(ctx context.Context) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&a.rpcMuxActive) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *App) startHttp(ctx context.Context) error {
+	// load router, rpc still needs http server in mux mode
+	if err := a.loadHttpRouter(); err != nil && !a.RpcHttpMux {
+		return nil
+	}
+
+	if a.HttpSslOn && !Config.IsSet("app.https_addr") {
+		return errors.New("https_addr is required when http_ssl_on is true")
+	}
+
+	if a.httpServer != nil {
 		// listen and serve
 		a.httpServer.Addr = Config.GetString("app.http_addr")
-		a.httpServer.Handler = a.httpEngine
+		a.httpServer.Handler = a.getHttpHandler(a.httpServer)
 
 		// defend slow dos attack
 		if Config.IsSet("app.http_read_timeout") {
@@ -463,22 +584,27 @@ func (a *App) runHttp() {
 		}
 
 		if Config.IsSet("app.http_read_header_timeout") {
-			a.httpServer.ReadHeaderTimeout= Config.GetDuration("app.http_read_header_timeout")
+			a.httpServer.ReadHeaderTimeout = Config.GetDuration("app.http_read_header_timeout")
+		}
+
+		ln, err := net.Listen("tcp", a.httpServer.Addr)
+		if err != nil {
+			return fmt.Errorf("http listen err: %s", err)
 		}
 
 		go func() {
 			// service connections
 			debugf("http listen on: %s\n", a.httpServer.Addr)
 
-			if err := a.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			if err := a.httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
 				fatalln("http listen err: ", err.Error())
 			}
 		}()
 	}
 
-	if hasHttps {
+	if a.httpsServer != nil {
 		a.httpsServer.Addr = Config.GetString("app.https_addr")
-		a.httpsServer.Handler = a.httpEngine
+		a.httpsServer.Handler = a.getHttpHandler(a.httpsServer)
 
 		// defend slow dos attack
 		if Config.IsSet("app.http_read_timeout") {
@@ -486,26 +612,29 @@ func (a *App) runHttp() {
 		}
 
 		if Config.IsSet("app.http_read_header_timeout") {
-			a.httpsServer.ReadTimeout = Config.GetDuration("app.http_read_header_timeout")
+			a.httpsServer.ReadHeaderTimeout = Config.GetDuration("app.http_read_header_timeout")
+		}
+
+		ln, err := net.Listen("tcp", a.httpsServer.Addr)
+		if err != nil {
+			return fmt.Errorf("https listen err: %s", err)
 		}
 
 		go func() {
 			// service connections
 			debugf("https listen on: %s\n", a.httpsServer.Addr)
 
-			if err := a.httpsServer.ListenAndServeTLS(a.HttpCertFile, a.HttpKeyFile); err != nil && err != http.ErrServerClosed {
+			if err := a.httpsServer.ServeTLS(ln, a.HttpCertFile, a.HttpKeyFile); err != nil && err != http.ErrServerClosed {
 				fatalf("https listen err: %s\n", err)
 			}
 		}()
 	}
 
-	<-a.httpCloseChan
-	ctx, cancel := context.WithTimeout(
-		context.Background(),
-		time.Duration(Config.GetInt64("app.http_stop_time_wait"))*time.Second,
-	)
-	defer cancel()
-	if hasHttp {
+	return nil
+}
+
+func (a *App) stopHttp(ctx context.Context) error {
+	if a.httpServer != nil {
 		if err := a.httpServer.Shutdown(ctx); err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
 				debug("http server already closed")
@@ -517,7 +646,7 @@ func (a *App) runHttp() {
 		}
 	}
 
-	if hasHttps {
+	if a.httpsServer != nil {
 		if err := a.httpsServer.Shutdown(ctx); err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
 				debug("https server already closed")
@@ -529,7 +658,7 @@ func (a *App) runHttp() {
 		}
 	}
 
-	a.httpCloseDoneChan <- 1
+	return nil
 }
 
 func (a *App) loadTaskRouter() error {
@@ -540,33 +669,30 @@ func (a *App) loadTaskRouter() error {
 	return nil
 }
 
-func (a *App) runTask() {
-	if !Config.GetBool("app.task_enable") {
-		return
-	}
-
+func (a *App) startTask(ctx context.Context) error {
 	if err := a.loadTaskRouter(); err != nil {
-		a.taskCloseDoneChan <- 1
-		return
+		return nil
 	}
 
-	c := cron.New()
-	wg := sync.WaitGroup{}
+	a.taskCron = cron.New()
 	for _, router := range TaskRouterList {
 		action := router.Action
 		name := runtime.FuncForPC(reflect.ValueOf(action).Pointer()).Name()
 		name = strings.NewReplacer("(", "", ")", "", "*", "").Replace(name)
+		if MetricsEnabled() {
+			action = taskMetricsHandler(name, action)
+		}
 		if router.Spec == "@loop" {
-			wg.Add(1)
+			a.taskWg.Add(1)
 			go func() {
-				defer wg.Done()
+				defer a.taskWg.Done()
 				action()
 				debugf("[TASK] %-32s --> %s\n", "stop", name)
 			}()
 		} else {
-			err := c.AddFunc(router.Spec, func() {
-				wg.Add(1)
-				defer wg.Done()
+			err := a.taskCron.AddFunc(router.Spec, func() {
+				a.taskWg.Add(1)
+				defer a.taskWg.Done()
 				action()
 			})
 			if err != nil {
@@ -576,51 +702,122 @@ func (a *App) runTask() {
 		debugf("[TASK] %-32s --> %s\n", router.Spec, name)
 	}
 
-	c.Start()
+	a.taskCron.Start()
+
+	return nil
+}
 
-	<-a.taskCloseChan
+func (a *App) stopTask(ctx context.Context) error {
+	if a.taskCron == nil {
+		return nil
+	}
+
+	a.taskCron.Stop()
+
+	// @loop task should exit when yago.StopChan is closed
+	done := make(chan struct{})
 	go func() {
-		c.Stop()
-		wg.Wait()
-		a.taskCloseDoneChan <- 1
+		a.taskWg.Wait()
+		close(done)
 	}()
-	time.Sleep(time.Duration(Config.GetInt64("app.task_stop_time_wait")) * time.Second)
-	a.taskCloseDoneChan <- 1
 
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-var RpcServer *grpc.Server
+var (
+	// interceptor chain of each method, built when rpc server starts
+	rpcUnaryChains  = make(map[string]grpc.UnaryServerInterceptor)
+	rpcStreamChains = make(map[string]grpc.StreamServerInterceptor)
+)
 
-func initGrpcServer() {
-	isSslOn := Config.GetBool("app.rpc_ssl_on")
-	if isSslOn {
-		certFile := Config.GetString("app.rpc_cert_file")
-		keyFile := Config.GetString("app.rpc_key_file")
-		if certFile == "" || keyFile == "" {
-			fatalln("rpc ssl cert file or key file is required when rpc ssl on")
-		}
-		cred, err := credentials.NewServerTLSFromFile(certFile, keyFile)
-		if err != nil {
-			fatalf("Failed to generate credentials %v", err)
-		}
+func rpcUnaryDispatcher(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	chain, ok := rpcUnaryChains[info.FullMethod]
+	if !ok {
+		// method registered after rpc server started, eg. reflection
+		chain, ok = rpcUnaryChains[""]
+	}
 
-		// 实例化 grpc Server, 并开启 TSL 认证
-		RpcServer = grpc.NewServer(grpc.Creds(cred))
+	if !ok {
+		return handler(ctx, req)
+	}
 
-	} else {
-		RpcServer = grpc.NewServer()
+	return chain(ctx, req, info, handler)
+}
+
+func rpcStreamDispatcher(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	chain, ok := rpcStreamChains[info.FullMethod]
+	if !ok {
+		chain, ok = rpcStreamChains[""]
+	}
+
+	if !ok {
+		return handler(srv, ss)
 	}
+
+	return chain(srv, ss, info, handler)
 }
 
-// rpc
-func (a *App) runRpc() {
-	rpcAddr := Config.GetString("app.rpc_addr")
-	lis, err := net.Listen("tcp", rpcAddr)
-	if err != nil {
-		fatalf("failed to listen: %v", err)
+// build interceptor chain for each registered method: default -> global -> service -> method
+func (a *App) loadRpcInterceptor() {
+	var globalUnary []grpc.UnaryServerInterceptor
+	var globalStream []grpc.StreamServerInterceptor
+
+	// metrics is the outermost, then the status code is final
+	if MetricsEnabled() {
+		globalUnary = append(globalUnary, rpcMetricsUnaryInterceptor)
+		globalStream = append(globalStream, rpcMetricsStreamInterceptor)
+	}
+
+	// trace is before the defaults, then logs carry the trace id
+	if TraceEnabled() {
+		globalUnary = append(globalUnary, rpcTraceUnaryInterceptor)
+		globalStream = append(globalStream, rpcTraceStreamInterceptor)
+	}
+
+	globalUnary = append(append(globalUnary, rpcDefaultInterceptors.Unary...), rpcGlobalInterceptors.Unary...)
+	globalStream = append(append(globalStream, rpcDefaultInterceptors.Stream...), rpcGlobalInterceptors.Stream...)
+
+	// empty key holds the global chain
+	if len(globalUnary) > 0 {
+		rpcUnaryChains[""] = grpcMiddleware.ChainUnaryServer(globalUnary...)
+	}
+	if len(globalStream) > 0 {
+		rpcStreamChains[""] = grpcMiddleware.ChainStreamServer(globalStream...)
 	}
 
-	a.rpcEngine = RpcServer
+	for service, info := range RpcRegistry.GetServiceInfo() {
+		for _, method := range info.Methods {
+			fullMethod := "/" + service + "/" + method.Name
+
+			unary := append([]grpc.UnaryServerInterceptor{}, globalUnary...)
+			stream := append([]grpc.StreamServerInterceptor{}, globalStream...)
+
+			for _, key := range []string{service, fullMethod} {
+				if r, ok := rpcScopedInterceptors[key]; ok {
+					unary = append(unary, r.Unary...)
+					stream = append(stream, r.Stream...)
+				}
+			}
+
+			if method.IsClientStream || method.IsServerStream {
+				if len(stream) > 0 {
+					rpcStreamChains[fullMethod] = grpcMiddleware.ChainStreamServer(stream...)
+				}
+			} else if len(unary) > 0 {
+				rpcUnaryChains[fullMethod] = grpcMiddleware.ChainUnaryServer(unary...)
+			}
+		}
+	}
+}
+
+// rpc
+func (a *App) loadRpcServer() {
+	a.rpcEngine = RpcRegistry.serve()
 
 	for k, v := range a.rpcEngine.GetServiceInfo() {
 		for _, method := range v.Methods {
@@ -628,44 +825,97 @@ func (a *App) runRpc() {
 		}
 	}
 
+	// health check, then load balancer can move connections away during deploys
+	healthpb.RegisterHealthServer(RpcRegistry, a.rpcHealthServer)
+	for service := range a.rpcEngine.GetServiceInfo() {
+		a.rpcHealthServer.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
 	// open rpc reflection, then you can use gpc_cli
 	rpcReflectOn := Config.GetBool("app.rpc_reflect_on")
 	if rpcReflectOn {
-		reflection.Register(RpcServer)
+		reflection.Register(RpcRegistry)
+	}
+
+}
+
+func (a *App) startRpc(ctx context.Context) error {
+	if a.RpcHttpMux && !a.HttpEnable {
+		return errors.New("http_enable is required when rpc_http_mux_on is true")
+	}
+
+	// rpc is served by http server in mux mode
+	if a.RpcHttpMux {
+		return nil
+	}
+
+	rpcAddr := Config.GetString("app.rpc_addr")
+	lis, err := net.Listen("tcp", rpcAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
 	}
 
 	go func() {
+		debugf("rpc listen on: %s\n", rpcAddr)
+
 		if err := a.rpcEngine.Serve(lis); err != nil {
 			fatalf("failed to serve: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+func (a *App) stopRpc(ctx context.Context) error {
+	a.rpcHealthServer.Shutdown()
+
+	// long-lived stream may block graceful stop forever, force stop after time wait
+	stopped := make(chan struct{})
+	go func() {
+		if a.RpcHttpMux {
+			// grpc GracefulStop does not support ServeHTTP, wait for active requests instead
+			a.waitRpcMuxRequests(ctx)
 		} else {
-			debugf("rpc listen on: %s\n", rpcAddr)
+			a.rpcEngine.GracefulStop()
 		}
+		close(stopped)
 	}()
 
-	<-a.rpcCloseChan
-	a.rpcEngine.GracefulStop()
-	a.rpcCloseDoneChan <- 1
+	select {
+	case <-stopped:
+		if a.RpcHttpMux {
+			a.rpcEngine.Stop()
+		}
+		return nil
+	case <-ctx.Done():
+		debug("rpc server gracefully stop timeout")
+		a.rpcEngine.Stop()
+		return ctx.Err()
+	}
 }
 
 func (a *App) Close() {
-	close(StopChan)
+	atomic.StoreInt32(&a.closing, 1)
 
-	if a.TaskEnable {
-		//close(TaskCloseChan)
-		a.taskCloseChan <- 1
-		<-a.taskCloseDoneChan
-		log.Println("Task Server Stop OK")
+	// mark rpc not serving as soon as shutdown begins
+	if a.RpcEnable {
+		a.rpcHealthServer.Shutdown()
 	}
-	if a.HttpEnable {
-		a.httpCloseChan <- 1
-		<-a.httpCloseDoneChan
-		log.Println("Http Server Stop OK")
+
+	// readyz 和 rpc health 返回失败后, 等待负载均衡摘除实例, 期间照常处理请求
+	if wait := getReadyDrainWait(); wait > 0 {
+		log.Printf("Wait %s For Ready Drain\n", wait)
+		time.Sleep(wait)
 	}
 
-	if a.RpcEnable {
-		a.rpcCloseChan <- 1
-		<-a.rpcCloseDoneChan
-		log.Println("Rpc Server Stop OK")
+	close(StopChan)
+	a.cancel()
+
+	a.stopServers()
+
+	// export the remaining spans
+	if err := tracelib.Shutdown(context.Background()); err != nil {
+		log.Println("Trace Exporter Shutdown Error:", err)
 	}
 
 	go func() {
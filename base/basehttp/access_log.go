@@ -0,0 +1,289 @@
+package basehttp
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "***"
+
+// 路由 metadata 实现此接口并返回 true 时, 不记录访问日志
+// eg. ghttp.Root.Get("/upload", h.UploadAction).WithMetadata(ghttp.HttpMetadata{NoAccessLog: true})
+type AccessLogDisabler interface {
+	DisableAccessLog() bool
+}
+
+type AccessLogConfig struct {
+	// 请求和响应 body 最多记录的字节数, 超出部分截断
+	BodyLimit int
+	// 成功请求的采样率 0 ~ 1, 失败请求全部记录
+	SampleRate float64
+	// 脱敏的 header, 不区分大小写
+	RedactHeaders []string
+	// 脱敏的 query, form 和 json 字段, 不区分大小写
+	RedactFields []string
+}
+
+// app.http_access_log_on 开启后作为默认中间件, 也可以只在路由组中使用
+// eg. yago.NewHttpGroupRouter("/api", basehttp.NewAccessLog(basehttp.DefaultAccessLogConfig()))
+func DefaultAccessLogConfig() *AccessLogConfig {
+	return &AccessLogConfig{
+		BodyLimit:     4096,
+		SampleRate:    1,
+		RedactHeaders: []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"},
+		RedactFields:  []string{"password", "phone"},
+	}
+}
+
+func loadAccessLogConfig() *AccessLogConfig {
+	conf := DefaultAccessLogConfig()
+
+	if yago.Config.IsSet("app.http_access_log_body_limit") {
+		conf.BodyLimit = yago.Config.GetInt("app.http_access_log_body_limit")
+	}
+	if yago.Config.IsSet("app.http_access_log_sample_rate") {
+		conf.SampleRate = yago.Config.GetFloat64("app.http_access_log_sample_rate")
+	}
+	if yago.Config.IsSet("app.http_access_log_redact_headers") {
+		conf.RedactHeaders = yago.Config.GetStringSlice("app.http_access_log_redact_headers")
+	}
+	if yago.Config.IsSet("app.http_access_log_redact_fields") {
+		conf.RedactFields = yago.Config.GetStringSlice("app.http_access_log_redact_fields")
+	}
+
+	return conf
+}
+
+type accessLog struct {
+	conf          *AccessLogConfig
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+	// 匹配 json 中需要脱敏的字段, body 被截断时也能脱敏
+	redactJson *regexp.Regexp
+}
+
+func NewAccessLog(conf *AccessLogConfig) yago.HttpHandlerFunc {
+	return newAccessLog(conf).handle
+}
+
+func newAccessLog(conf *AccessLogConfig) *accessLog {
+	l := &accessLog{
+		conf:          conf,
+		redactHeaders: make(map[string]bool),
+		redactFields:  make(map[string]bool),
+	}
+
+	for _, h := range conf.RedactHeaders {
+		l.redactHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+
+	quoted := make([]string, 0, len(conf.RedactFields))
+	for _, f := range conf.RedactFields {
+		l.redactFields[strings.ToLower(f)] = true
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+
+	if len(quoted) > 0 {
+		l.redactJson = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+
+	return l
+}
+
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	limit int
+	body  *bytes.Buffer
+}
+
+func (w *bodyLogWriter) capture(b []byte) {
+	if remain := w.limit - w.body.Len(); remain > 0 {
+		if len(b) > remain {
+			b = b[:remain]
+		}
+		w.body.Write(b)
+	}
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (l *accessLog) handle(c *yago.Ctx) {
+	// 不记录时不包装 writer, 也不读取 body, 上传和流式响应保持原样
+	if r, ok := c.GetHttpRouter(); ok {
+		if d, ok := r.Metadata.(AccessLogDisabler); ok && d.DisableAccessLog() {
+			c.Next()
+			return
+		}
+	}
+
+	begin := time.Now()
+
+	reqBody := l.readRequestBody(c)
+
+	writer := &bodyLogWriter{ResponseWriter: c.Writer, limit: l.conf.BodyLimit, body: new(bytes.Buffer)}
+	c.Writer = writer
+
+	c.Next()
+
+	status := c.Writer.Status()
+	errno := 0
+	if resp, ok := c.GetResponse(); ok {
+		errno = resp.ErrNo
+	}
+
+	failed := status >= http.StatusBadRequest || errno != 0
+	if !failed && l.conf.SampleRate < 1 && rand.Float64() >= l.conf.SampleRate {
+		return
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = "NoRoute"
+	}
+
+	logInfo := logrus.Fields{
+		"category":        "http.server",
+		"method":          c.Request.Method,
+		"route":           route,
+		"path":            c.Request.URL.Path,
+		"query":           l.redactQuery(c.Request.URL.RawQuery),
+		"status":          status,
+		"errno":           errno,
+		"consume":         time.Since(begin).Nanoseconds() / 1e6,
+		"ip":              c.ClientIP(),
+		"request_id":      c.GetRequestId(),
+		"header":          l.redactHeader(c.Request.Header),
+		"body":            reqBody,
+		"response_header": l.redactHeader(c.Writer.Header()),
+		"response":        l.redactBody(c.Writer.Header().Get("Content-Type"), writer.body.Bytes(), writer.body.Len() >= l.conf.BodyLimit),
+	}
+
+	entry := logger.Ins().WithContext(c.Request.Context()).WithFields(logInfo)
+	if failed {
+		if err := c.GetError(); err != nil {
+			entry = entry.WithField("hint", err.Error())
+		}
+		entry.Error()
+	} else {
+		entry.Info()
+	}
+}
+
+// 只记录文本类型的 body, 读取 limit 字节后与剩余的 body 拼接还原
+func (l *accessLog) readRequestBody(c *yago.Ctx) string {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return ""
+	}
+
+	contentType := c.ContentType()
+	if !isTextContent(contentType) {
+		return ""
+	}
+
+	body := c.Request.Body
+	buf := make([]byte, l.conf.BodyLimit)
+	n, err := io.ReadFull(body, buf)
+	buf = buf[:n]
+	truncated := err == nil
+
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), body), body}
+
+	return l.redactBody(contentType, buf, truncated)
+}
+
+func isTextContent(contentType string) bool {
+	switch {
+	case strings.Contains(contentType, "json"),
+		strings.Contains(contentType, "xml"),
+		strings.HasPrefix(contentType, "text/"),
+		contentType == gin.MIMEPOSTForm:
+		return true
+	}
+	return false
+}
+
+func (l *accessLog) redactBody(contentType string, body []byte, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var s string
+	switch {
+	case strings.Contains(contentType, gin.MIMEPOSTForm):
+		s = l.redactQuery(string(body))
+	case strings.Contains(contentType, "json"):
+		s = string(body)
+		if l.redactJson != nil {
+			s = l.redactJson.ReplaceAllString(s, `${1}"`+redacted+`"`)
+		}
+	case isTextContent(contentType):
+		s = string(body)
+	default:
+		return ""
+	}
+
+	if truncated {
+		s += "...(truncated)"
+	}
+
+	return s
+}
+
+func (l *accessLog) redactQuery(rawQuery string) string {
+	if rawQuery == "" || len(l.redactFields) == 0 {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	changed := false
+	for k := range values {
+		if l.redactFields[strings.ToLower(k)] {
+			values[k] = []string{redacted}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return rawQuery
+	}
+
+	return strings.ReplaceAll(values.Encode(), url.QueryEscape(redacted), redacted)
+}
+
+func (l *accessLog) redactHeader(header http.Header) http.Header {
+	h := make(http.Header, len(header))
+	for k, v := range header {
+		if l.redactHeaders[http.CanonicalHeaderKey(k)] {
+			h[k] = []string{redacted}
+		} else {
+			h[k] = v
+		}
+	}
+	return h
+}
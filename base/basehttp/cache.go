@@ -0,0 +1,450 @@
+package basehttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+)
+
+type ResponseCacheRule struct {
+	// 路由模板, eg. "/user/detail/:id", 只缓存 GET 请求的成功响应
+	Route string        `mapstructure:"route"`
+	TTL   time.Duration `mapstructure:"ttl"`
+	// 参与缓存 key 的 query 参数, 其他参数不影响缓存, ["*"] 为所有参数
+	Query []string `mapstructure:"query"`
+	// 按用户缓存, 需要在 auth 中间件之后使用, 没有登录用户时不读写缓存
+	PerUser bool `mapstructure:"per_user"`
+	// 标签, {name} 替换为路由参数或 query 参数, eg. "user:{id}"
+	Tags []string `mapstructure:"tags"`
+}
+
+var (
+	defaultResponseCacheStore     ResponseCacheStore
+	defaultResponseCacheStoreOnce sync.Once
+)
+
+// 按 http_cache 配置段创建, 未配置时使用单机缓存
+func DefaultResponseCacheStore() ResponseCacheStore {
+	defaultResponseCacheStoreOnce.Do(func() {
+		defaultResponseCacheStore = newResponseCacheStoreFromConfig("http_cache")
+	})
+	return defaultResponseCacheStore
+}
+
+func newResponseCacheStoreFromConfig(section string) ResponseCacheStore {
+	switch driver := yago.Config.GetString(section + ".driver"); driver {
+	case "memory", "":
+		return NewMemoryResponseCacheStore(yago.Config.GetInt(section + ".max_entries"))
+	case "redis":
+		rdsId := yago.Config.GetString(section + ".redis_instance_id")
+		if rdsId == "" {
+			rdsId = "redis"
+		}
+		prefix := yago.Config.GetString(section + ".prefix")
+		if prefix == "" {
+			prefix = "http_cache:"
+		}
+		return NewRedisResponseCacheStore(rdsId, prefix)
+	default:
+		log.Fatalf("Fatal error: unsupported http_cache driver %s", driver)
+	}
+	return nil
+}
+
+// 数据变更后在 service 中调用, 删除 DefaultResponseCacheStore 中带有这些标签的缓存
+// eg. basehttp.InvalidateResponseCache("user:" + strconv.FormatInt(id, 10))
+func InvalidateResponseCache(tags ...string) error {
+	return DefaultResponseCacheStore().InvalidateTags(tags...)
+}
+
+// 在单个路由上使用, store 为空时使用 DefaultResponseCacheStore
+// eg. ghttp.Root.Get("/user/detail/:id", basehttp.NewResponseCache(basehttp.ResponseCacheRule{TTL: time.Minute, Tags: []string{"user:{id}"}}), h.DetailAction)
+func NewResponseCache(rule ResponseCacheRule, store ...ResponseCacheStore) yago.HttpHandlerFunc {
+	if rule.TTL <= 0 {
+		log.Fatalf("Fatal error: http_cache route %s ttl must be positive", rule.Route)
+	}
+
+	var s ResponseCacheStore
+	if len(store) > 0 {
+		s = store[0]
+	}
+
+	return func(c *yago.Ctx) {
+		if s == nil {
+			responseCache(c, DefaultResponseCacheStore(), &rule)
+			return
+		}
+		responseCache(c, s, &rule)
+	}
+}
+
+// 按配置段中的 routes 缓存, 按用户缓存时需要在 auth 中间件之后使用
+// eg. yago.NewHttpGroupRouter("/api", auth.Ins().Middleware(), basehttp.NewRouteResponseCache("http_cache"))
+func NewRouteResponseCache(section string) yago.HttpHandlerFunc {
+	return newRouteResponseCache(section, false)
+}
+
+// app.http_cache_on 开启后作为默认中间件, 默认中间件在 auth 之前执行, 不能按用户缓存
+func newRouteResponseCache(section string, defaultMiddleware bool) yago.HttpHandlerFunc {
+	rules := make([]ResponseCacheRule, 0)
+	if err := yago.Config.UnmarshalKey(section+".routes", &rules); err != nil {
+		log.Fatalf("Fatal error: parse %s.routes err: %s", section, err)
+	}
+
+	routes := make(map[string]*ResponseCacheRule, len(rules))
+	for i, r := range rules {
+		// 可以带请求方法, 只有 GET 生效
+		route := strings.Fields(r.Route)
+		if len(route) == 0 || r.TTL <= 0 {
+			log.Fatalf("Fatal error: http_cache route %s and ttl are required", r.Route)
+		}
+		if r.PerUser && defaultMiddleware {
+			log.Fatalf("Fatal error: http_cache route %s with per_user can not be used by app.http_cache_on, use basehttp.NewRouteResponseCache after auth middleware", r.Route)
+		}
+		routes[route[len(route)-1]] = &rules[i]
+	}
+
+	return func(c *yago.Ctx) {
+		r, ok := routes[c.FullPath()]
+		if !ok {
+			return
+		}
+
+		responseCache(c, DefaultResponseCacheStore(), r)
+	}
+}
+
+type cachedResponse struct {
+	Status       int    `json:"status"`
+	ContentType  string `json:"content_type"`
+	Body         []byte `json:"body"`
+	ETag         string `json:"etag"`
+	LastModified int64  `json:"last_modified"`
+	ExpireAt     int64  `json:"expire_at"`
+}
+
+// 请求中的 Cache-Control
+type requestCacheControl struct {
+	noStore      bool
+	noCache      bool
+	onlyIfCached bool
+	// 为 -1 时未设置
+	maxAge   int64
+	minFresh int64
+}
+
+func parseRequestCacheControl(h http.Header) *requestCacheControl {
+	cc := &requestCacheControl{maxAge: -1, minFresh: -1}
+
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value := strings.TrimSpace(directive), ""
+			if i := strings.Index(name, "="); i >= 0 {
+				name, value = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+			}
+
+			switch strings.ToLower(name) {
+			case "no-store":
+				cc.noStore = true
+			case "no-cache":
+				cc.noCache = true
+			case "only-if-cached":
+				cc.onlyIfCached = true
+			case "max-age":
+				if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+					cc.maxAge = n
+				}
+			case "min-fresh":
+				if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+					cc.minFresh = n
+				}
+			}
+		}
+	}
+
+	if len(h.Values("Cache-Control")) == 0 && strings.EqualFold(h.Get("Pragma"), "no-cache") {
+		cc.noCache = true
+	}
+
+	return cc
+}
+
+// 缓存命中时回放, 否则缓存成功的响应
+// 客户端 Cache-Control: no-store 不读写缓存, no-cache 或 max-age 超出时重新执行并更新缓存
+// 响应带 ETag 和 Last-Modified, If-None-Match 和 If-Modified-Since 匹配时返回 304
+func responseCache(c *yago.Ctx, store ResponseCacheStore, rule *ResponseCacheRule) {
+	if c.Request.Method != http.MethodGet {
+		return
+	}
+
+	// 按用户缓存但没有登录用户时, 不能确定响应属于谁, 不读写缓存
+	key, ok := responseCacheKey(c, rule)
+	if !ok {
+		return
+	}
+
+	cc := parseRequestCacheControl(c.Request.Header)
+	now := time.Now()
+
+	if !cc.noStore && !cc.noCache {
+		cached, err := getCachedResponse(store, key)
+		if err != nil {
+			logger.Ins().Category("http.cache").WithContext(c.Request.Context()).Errorf("get cache %s err: %s", key, err)
+		}
+
+		if cached != nil && cachedResponseAcceptable(cached, cc, now) {
+			c.Header("X-Cache", "HIT")
+			c.Header("Age", strconv.FormatInt(now.Unix()-cached.LastModified, 10))
+			writeCachedResponse(c, cached, rule, now)
+			c.Abort()
+			return
+		}
+	}
+
+	if cc.onlyIfCached {
+		c.AbortWithStatus(http.StatusGatewayTimeout)
+		return
+	}
+
+	writer := &cacheResponseWriter{ResponseWriter: c.Writer, body: new(bytes.Buffer)}
+	c.Writer = writer
+
+	// handler panic 时恢复 writer, 已缓冲的内容照常写出, 没有内容时由 recovery 返回 ErrSystem
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		c.Writer = writer.ResponseWriter
+		if writer.body.Len() > 0 {
+			writer.flush()
+		}
+	}()
+
+	c.Next()
+
+	completed = true
+	c.Writer = writer.ResponseWriter
+
+	resp, ok := c.GetResponse()
+	status := writer.Status()
+	if !ok || resp.ErrNo != 0 || status != http.StatusOK || responseNotCacheable(c.Writer.Header()) {
+		writer.flush()
+		return
+	}
+
+	cached := &cachedResponse{
+		Status:       status,
+		ContentType:  c.Writer.Header().Get("Content-Type"),
+		Body:         writer.body.Bytes(),
+		ETag:         responseETag(writer.body.Bytes()),
+		LastModified: now.Unix(),
+		ExpireAt:     now.Add(rule.TTL).Unix(),
+	}
+
+	if !cc.noStore {
+		b, err := json.Marshal(cached)
+		if err == nil {
+			err = store.Set(key, b, rule.TTL, responseCacheTags(c, rule))
+		}
+		if err != nil {
+			logger.Ins().Category("http.cache").WithContext(c.Request.Context()).Errorf("set cache %s err: %s", key, err)
+		}
+	}
+
+	c.Header("X-Cache", "MISS")
+	writeCachedResponse(c, cached, rule, now)
+}
+
+func getCachedResponse(store ResponseCacheStore, key string) (*cachedResponse, error) {
+	b, err := store.Get(key)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(b, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// 按客户端的 max-age 和 min-fresh 判断缓存是否可用
+func cachedResponseAcceptable(cached *cachedResponse, cc *requestCacheControl, now time.Time) bool {
+	if now.Unix() >= cached.ExpireAt {
+		return false
+	}
+	if cc.maxAge >= 0 && now.Unix()-cached.LastModified > cc.maxAge {
+		return false
+	}
+	if cc.minFresh >= 0 && cached.ExpireAt-now.Unix() < cc.minFresh {
+		return false
+	}
+	return true
+}
+
+// handler 设置了 Cache-Control: no-store 或 private 时不缓存
+func responseNotCacheable(h http.Header) bool {
+	v := strings.ToLower(h.Get("Cache-Control"))
+	return strings.Contains(v, "no-store") || strings.Contains(v, "private")
+}
+
+func writeCachedResponse(c *yago.Ctx, cached *cachedResponse, rule *ResponseCacheRule, now time.Time) {
+	maxAge := cached.ExpireAt - now.Unix()
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	cacheControl := "max-age=" + strconv.FormatInt(maxAge, 10)
+	if rule.PerUser {
+		cacheControl = "private, " + cacheControl
+	}
+
+	lastModified := time.Unix(cached.LastModified, 0)
+	c.Header("ETag", cached.ETag)
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", cacheControl)
+	c.Header("Vary", "Accept, Accept-Language")
+
+	if responseNotModified(c.Request.Header, cached.ETag, lastModified) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Data(cached.Status, cached.ContentType, cached.Body)
+}
+
+// If-None-Match 优先于 If-Modified-Since, 按 RFC 7232 弱比较 ETag
+func responseNotModified(h http.Header, etag string, lastModified time.Time) bool {
+	if inm := h.Get("If-None-Match"); inm != "" {
+		for _, v := range strings.Split(inm, ",") {
+			v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+			if v == "*" || v == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := h.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.After(t)
+	}
+
+	return false
+}
+
+func responseETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// 路由模板, 路由参数, 选择的 query 参数, 响应格式, 语言和用户, 按用户缓存但没有登录用户时返回 false
+func responseCacheKey(c *yago.Ctx, rule *ResponseCacheRule) (string, bool) {
+	var user string
+	if rule.PerUser {
+		if user = RateLimitByUser(c); user == "" {
+			return "", false
+		}
+	}
+
+	var b strings.Builder
+
+	for _, p := range c.Params {
+		b.WriteString(p.Key + "=" + p.Value + "&")
+	}
+	b.WriteString("\n")
+
+	query := c.Request.URL.Query()
+	if len(rule.Query) != 1 || rule.Query[0] != "*" {
+		selected := make(url.Values, len(rule.Query))
+		for _, name := range rule.Query {
+			if v, ok := query[name]; ok {
+				selected[name] = v
+			}
+		}
+		query = selected
+	}
+	// Encode 按参数名排序
+	b.WriteString(query.Encode() + "\n")
+
+	b.WriteString(c.RenderContentType() + "\n")
+	b.WriteString(c.GetLang() + "\n")
+	b.WriteString(user)
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return c.FullPath() + ":" + hex.EncodeToString(sum[:16]), true
+}
+
+func responseCacheTags(c *yago.Ctx, rule *ResponseCacheRule) []string {
+	tags := make([]string, 0, len(rule.Tags))
+	for _, tag := range rule.Tags {
+		tags = append(tags, expandCacheTag(c, tag))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// 替换 {name} 为路由参数或 query 参数
+func expandCacheTag(c *yago.Ctx, tag string) string {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(tag, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(tag[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		name := tag[start+1 : end]
+		value, ok := c.Params.Get(name)
+		if !ok {
+			value = c.Query(name)
+		}
+
+		b.WriteString(tag[:start] + value)
+		tag = tag[end+1:]
+	}
+	b.WriteString(tag)
+
+	return b.String()
+}
+
+// 缓冲响应, 计算 ETag 后再写入
+type cacheResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *cacheResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *cacheResponseWriter) WriteHeaderNow() {}
+
+func (w *cacheResponseWriter) flush() {
+	w.ResponseWriter.WriteHeaderNow()
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,219 @@
+package basehttp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/hulklab/yago/coms/rds"
+)
+
+// 保存缓存的响应, 按标签批量失效
+type ResponseCacheStore interface {
+	// key 不存在时返回 nil, nil
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration, tags []string) error
+	// 删除带有任一标签的缓存
+	InvalidateTags(tags ...string) error
+}
+
+type memoryCacheEntry struct {
+	value    []byte
+	expireAt time.Time
+	tags     []string
+}
+
+type memoryResponseCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryCacheEntry
+	// 标签 => 带有该标签的 key
+	tags       map[string]map[string]struct{}
+	maxEntries int
+	// 上次清理过期缓存的时间
+	swept time.Time
+}
+
+// 单机缓存, 最多保存 maxEntries 个响应, 超出时不再写入
+func NewMemoryResponseCacheStore(maxEntries int) ResponseCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	return &memoryResponseCacheStore{
+		entries:    make(map[string]*memoryCacheEntry),
+		tags:       make(map[string]map[string]struct{}),
+		maxEntries: maxEntries,
+		swept:      time.Now(),
+	}
+}
+
+func (s *memoryResponseCacheStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(e.expireAt) {
+		s.delete(key)
+		return nil, nil
+	}
+
+	return e.value, nil
+}
+
+func (s *memoryResponseCacheStore) Set(key string, value []byte, ttl time.Duration, tags []string) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) >= time.Minute || len(s.entries) >= s.maxEntries {
+		s.sweep(now)
+	}
+
+	if _, ok := s.entries[key]; ok {
+		s.delete(key)
+	} else if len(s.entries) >= s.maxEntries {
+		return nil
+	}
+
+	s.entries[key] = &memoryCacheEntry{value: value, expireAt: now.Add(ttl), tags: tags}
+	for _, tag := range tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *memoryResponseCacheStore) InvalidateTags(tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			s.delete(key)
+		}
+		delete(s.tags, tag)
+	}
+
+	return nil
+}
+
+func (s *memoryResponseCacheStore) delete(key string) {
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	delete(s.entries, key)
+
+	for _, tag := range e.tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
+
+func (s *memoryResponseCacheStore) sweep(now time.Time) {
+	s.swept = now
+
+	for key, e := range s.entries {
+		if now.After(e.expireAt) {
+			s.delete(key)
+		}
+	}
+}
+
+type redisResponseCacheStore struct {
+	rdsId  string
+	prefix string
+}
+
+// 多实例共享的缓存, 标签保存为 set, 失效时删除 set 中的 key
+// 缓存的 key 不在 lua 脚本的 KEYS 中, 不支持 redis cluster
+func NewRedisResponseCacheStore(rdsId, prefix string) ResponseCacheStore {
+	return &redisResponseCacheStore{rdsId: rdsId, prefix: prefix}
+}
+
+// KEYS[1] 缓存 key, KEYS[2:] 标签 key, ARGV: 缓存内容, 毫秒数
+// 标签 set 的过期时间取其中缓存最长的
+var cacheSetScript = redis.NewScript(-1, `
+	local ttl = tonumber(ARGV[2])
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+
+	for i = 2, #KEYS do
+		redis.call("SADD", KEYS[i], KEYS[1])
+		if redis.call("PTTL", KEYS[i]) < ttl then
+			redis.call("PEXPIRE", KEYS[i], ttl)
+		end
+	end
+
+	return 1
+`)
+
+// KEYS 标签 key, 删除标签 set 和其中的缓存 key
+var cacheInvalidateScript = redis.NewScript(-1, `
+	for i = 1, #KEYS do
+		local keys = redis.call("SMEMBERS", KEYS[i])
+		for j = 1, #keys, 500 do
+			redis.call("DEL", unpack(keys, j, math.min(j + 499, #keys)))
+		end
+		redis.call("DEL", KEYS[i])
+	end
+
+	return 1
+`)
+
+func (s *redisResponseCacheStore) Get(key string) ([]byte, error) {
+	b, err := redis.Bytes(rds.Ins(s.rdsId).Get(s.prefix + key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (s *redisResponseCacheStore) Set(key string, value []byte, ttl time.Duration, tags []string) error {
+	args := make([]interface{}, 0, len(tags)+4)
+	args = append(args, len(tags)+1, s.prefix+key)
+	for _, tag := range tags {
+		args = append(args, s.tagKey(tag))
+	}
+	args = append(args, value, ttl.Milliseconds())
+
+	rc := rds.Ins(s.rdsId).GetConn()
+	defer rc.Close()
+
+	_, err := cacheSetScript.Do(rc, args...)
+	return err
+}
+
+func (s *redisResponseCacheStore) InvalidateTags(tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(tags)+1)
+	args = append(args, len(tags))
+	for _, tag := range tags {
+		args = append(args, s.tagKey(tag))
+	}
+
+	rc := rds.Ins(s.rdsId).GetConn()
+	defer rc.Close()
+
+	_, err := cacheInvalidateScript.Do(rc, args...)
+	return err
+}
+
+func (s *redisResponseCacheStore) tagKey(tag string) string {
+	return s.prefix + "tag:" + tag
+}
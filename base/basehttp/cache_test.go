@@ -0,0 +1,332 @@
+package basehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/auth"
+)
+
+// go test -v ./base/basehttp -test.run TestResponseCache
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	yago.LoadErrCodes()
+}
+
+func ginHandler(h yago.HttpHandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h(&yago.Ctx{Context: c})
+	}
+}
+
+func newTestCtx(target string, params gin.Params) *yago.Ctx {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	c.Params = params
+	return &yago.Ctx{Context: c}
+}
+
+type cacheTestServer struct {
+	engine *gin.Engine
+	calls  int
+}
+
+// /user/:id 返回调用次数, 带 user header 时设置登录用户, panic=1 时 panic, err=1 时返回错误, private=1 时设置 Cache-Control: private
+func newCacheTestServer(rule ResponseCacheRule) *cacheTestServer {
+	s := &cacheTestServer{engine: gin.New()}
+
+	setUser := func(c *yago.Ctx) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			auth.SetPrincipal(c, &auth.Principal{Subject: user})
+		}
+	}
+
+	// 与 yago 的 recovery 一致, 没有写入响应时返回 ErrSystem
+	recovery := func(c *gin.Context) {
+		defer func() {
+			if recover() != nil && !c.Writer.Written() {
+				(&yago.Ctx{Context: c}).AbortWithE(yago.ErrSystem)
+			}
+		}()
+		c.Next()
+	}
+
+	s.engine.GET("/user/:id",
+		recovery,
+		ginHandler(setUser),
+		ginHandler(NewResponseCache(rule, NewMemoryResponseCacheStore(0))),
+		ginHandler(func(c *yago.Ctx) {
+			s.calls++
+			if c.Query("panic") != "" {
+				panic("test panic")
+			}
+			if c.Query("err") != "" {
+				c.SetError(yago.ErrParam)
+				return
+			}
+			if c.Query("private") != "" {
+				c.Header("Cache-Control", "private")
+			}
+			c.SetData(map[string]interface{}{"id": c.Param("id"), "calls": s.calls})
+		}),
+	)
+
+	return s
+}
+
+func (s *cacheTestServer) get(target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	rule := &ResponseCacheRule{Query: []string{"fields"}}
+	key := func(target string, params gin.Params) string {
+		k, ok := responseCacheKey(newTestCtx(target, params), rule)
+		if !ok {
+			t.Fatalf("key of %s should be ok", target)
+		}
+		return k
+	}
+
+	id1 := gin.Params{{Key: "id", Value: "1"}}
+	base := key("/user/1?fields=name", id1)
+
+	if got := key("/user/1?fields=name&_t=123", id1); got != base {
+		t.Error("query not selected should not change the key")
+	}
+	if got := key("/user/1?fields=phone", id1); got == base {
+		t.Error("selected query should change the key")
+	}
+	if got := key("/user/2?fields=name", gin.Params{{Key: "id", Value: "2"}}); got == base {
+		t.Error("path params should change the key")
+	}
+
+	rule.Query = []string{"*"}
+	if key("/user/1?fields=name&_t=1", id1) == key("/user/1?fields=name&_t=2", id1) {
+		t.Error("all query should change the key with *")
+	}
+	if key("/user/1?a=1&b=2", id1) != key("/user/1?b=2&a=1", id1) {
+		t.Error("query order should not change the key")
+	}
+
+	langKey := func(lang string) string {
+		c := newTestCtx("/user/1?fields=name", id1)
+		c.Request.Header.Set("Accept-Language", lang)
+		k, _ := responseCacheKey(c, rule)
+		return k
+	}
+	if langKey("en-US,en;q=0.9") == langKey("zh-CN") {
+		t.Error("language should change the key")
+	}
+
+	rule.PerUser = true
+	if _, ok := responseCacheKey(newTestCtx("/user/1", id1), rule); ok {
+		t.Error("per user key without principal should not be ok")
+	}
+
+	userKey := func(user string) string {
+		c := newTestCtx("/user/1", id1)
+		auth.SetPrincipal(c, &auth.Principal{Subject: user})
+		k, ok := responseCacheKey(c, rule)
+		if !ok {
+			t.Fatalf("per user key of %s should be ok", user)
+		}
+		return k
+	}
+	if userKey("alice") == userKey("bob") {
+		t.Error("per user keys of different users should be different")
+	}
+}
+
+func TestResponseCache_Conditional(t *testing.T) {
+	s := newCacheTestServer(ResponseCacheRule{TTL: time.Minute})
+
+	w := s.get("/user/1", nil)
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	if w.Code != http.StatusOK || w.Header().Get("X-Cache") != "MISS" || etag == "" || lastModified == "" {
+		t.Fatalf("first request got %d %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+
+	w = s.get("/user/1", nil)
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != body || w.Header().Get("ETag") != etag {
+		t.Errorf("second request should hit cache, got %v %s", w.Header(), w.Body.String())
+	}
+
+	cases := []struct {
+		headers map[string]string
+		status  int
+	}{
+		{map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{map[string]string{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}, http.StatusOK},
+		// If-None-Match 优先
+		{map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified}, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		w := s.get("/user/1", c.headers)
+		if w.Code != c.status {
+			t.Errorf("request with %v got %d, want %d", c.headers, w.Code, c.status)
+		}
+		if c.status == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("304 should have no body, got %s", w.Body.String())
+		}
+	}
+
+	if s.calls != 1 {
+		t.Errorf("handler should be called once, got %d", s.calls)
+	}
+
+	// 未命中缓存时也按条件请求返回 304
+	w = s.get("/user/2", map[string]string{"If-None-Match": "*"})
+	if w.Code != http.StatusNotModified || w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("miss with If-None-Match got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestResponseCache_CacheControl(t *testing.T) {
+	s := newCacheTestServer(ResponseCacheRule{TTL: time.Minute, Query: []string{"err", "private"}})
+
+	// no-store 不读写缓存
+	s.get("/user/1", map[string]string{"Cache-Control": "no-store"})
+	if w := s.get("/user/1", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("response with no-store should not be stored")
+	}
+	if w := s.get("/user/1", map[string]string{"Cache-Control": "no-store"}); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("request with no-store should not read cache")
+	}
+
+	// no-cache 重新执行并更新缓存
+	calls := s.calls
+	if w := s.get("/user/1", map[string]string{"Cache-Control": "no-cache"}); w.Header().Get("X-Cache") != "MISS" || s.calls != calls+1 {
+		t.Error("request with no-cache should not read cache")
+	}
+	if w := s.get("/user/1", map[string]string{"Pragma": "no-cache"}); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("request with Pragma: no-cache should not read cache")
+	}
+	if w := s.get("/user/1", map[string]string{"Cache-Control": "max-age=60"}); w.Header().Get("X-Cache") != "HIT" {
+		t.Error("request with max-age should read fresh cache")
+	}
+
+	// 剩余时间不足 min-fresh
+	if w := s.get("/user/1", map[string]string{"Cache-Control": "min-fresh=120"}); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("request with min-fresh over ttl should not read cache")
+	}
+
+	// only-if-cached 未命中返回 504
+	if w := s.get("/user/3", map[string]string{"Cache-Control": "only-if-cached"}); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("only-if-cached without cache got %d", w.Code)
+	}
+	if w := s.get("/user/1", map[string]string{"Cache-Control": "only-if-cached"}); w.Code != http.StatusOK {
+		t.Errorf("only-if-cached with cache got %d", w.Code)
+	}
+
+	// 错误和 handler 设置了 private 的响应不缓存
+	for _, target := range []string{"/user/1?err=1", "/user/1?private=1"} {
+		s.get(target, nil)
+		if w := s.get(target, nil); w.Header().Get("X-Cache") == "HIT" {
+			t.Errorf("%s should not be cached", target)
+		}
+	}
+
+	if w := s.get("/user/1", nil); w.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("response Cache-Control got %s", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestResponseCache_Panic(t *testing.T) {
+	s := newCacheTestServer(ResponseCacheRule{TTL: time.Minute, Query: []string{"panic"}})
+
+	w := s.get("/user/1?panic=1", nil)
+	if !strings.Contains(w.Body.String(), `"errno":`+strconv.Itoa(yago.ErrSystem.Code())) {
+		t.Errorf("panic response got %d %q", w.Code, w.Body.String())
+	}
+	if w := s.get("/user/1?panic=1", nil); w.Header().Get("X-Cache") == "HIT" {
+		t.Error("panic response should not be cached")
+	}
+}
+
+func TestResponseCache_Vary(t *testing.T) {
+	s := newCacheTestServer(ResponseCacheRule{TTL: time.Minute})
+
+	w := s.get("/user/1", map[string]string{"Accept-Language": "en"})
+	if vary := w.Header().Get("Vary"); !strings.Contains(vary, "Accept-Language") {
+		t.Errorf("Vary got %s", vary)
+	}
+	if w := s.get("/user/1", map[string]string{"Accept-Language": "zh"}); w.Header().Get("X-Cache") != "MISS" {
+		t.Error("cache should not be shared between languages")
+	}
+	if w := s.get("/user/1", map[string]string{"Accept-Language": "en"}); w.Header().Get("X-Cache") != "HIT" {
+		t.Error("cache should be hit by the same language")
+	}
+}
+
+func TestResponseCache_PerUser(t *testing.T) {
+	s := newCacheTestServer(ResponseCacheRule{TTL: time.Minute, PerUser: true})
+
+	// 没有登录用户时不读写缓存
+	for i := 0; i < 2; i++ {
+		if w := s.get("/user/1", nil); w.Header().Get("X-Cache") != "" {
+			t.Errorf("anonymous request should skip cache, got %s", w.Header().Get("X-Cache"))
+		}
+	}
+
+	alice := map[string]string{"X-Test-User": "alice"}
+	bob := map[string]string{"X-Test-User": "bob"}
+
+	aliceBody := s.get("/user/1", alice).Body.String()
+	if w := s.get("/user/1", bob); w.Header().Get("X-Cache") != "MISS" || w.Body.String() == aliceBody {
+		t.Error("cache should not be shared between users")
+	}
+	if w := s.get("/user/1", alice); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != aliceBody {
+		t.Error("cache should be hit by the same user")
+	}
+	if w := s.get("/user/1", alice); w.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Errorf("per user response Cache-Control got %s", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestMemoryResponseCacheStore(t *testing.T) {
+	s := NewMemoryResponseCacheStore(2)
+
+	_ = s.Set("a", []byte("a"), time.Minute, []string{"user:1"})
+	_ = s.Set("b", []byte("b"), time.Minute, []string{"user:1", "user:2"})
+	_ = s.Set("c", []byte("c"), time.Minute, nil)
+
+	if v, _ := s.Get("c"); v != nil {
+		t.Error("store should not exceed max entries")
+	}
+
+	_ = s.InvalidateTags("user:2")
+	if v, _ := s.Get("b"); v != nil {
+		t.Error("b should be invalidated by user:2")
+	}
+	if v, _ := s.Get("a"); string(v) != "a" {
+		t.Error("a should not be invalidated by user:2")
+	}
+
+	_ = s.Set("d", []byte("d"), time.Millisecond, nil)
+	time.Sleep(2 * time.Millisecond)
+	if v, _ := s.Get("d"); v != nil {
+		t.Error("d should be expired")
+	}
+}
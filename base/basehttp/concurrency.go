@@ -0,0 +1,46 @@
+package basehttp
+
+import (
+	"errors"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/libs/semalib"
+)
+
+// 路由 metadata 实现此接口设置并发限制中的优先级, 上限不足时先拒绝批量请求, 最后拒绝重要请求
+// eg. ghttp.Root.Post("/order/create", h.CreateAction).WithMetadata(ghttp.HttpMetadata{Priority: semalib.PriorityCritical})
+type ConcurrencyPrioritizer interface {
+	ConcurrencyPriority() semalib.Priority
+}
+
+// 并发超出上限时返回 ErrOverloaded, 等待中请求的 ctx 超时返回 ErrTimeout, priority 为路由组的默认优先级, 路由 metadata 优先
+// app.http_concurrency_limit_on 开启后使用 yago.ConcurrencyLimiter() 作为默认中间件
+// eg. yago.NewHttpGroupRouter("/report", basehttp.NewConcurrencyLimit(yago.ConcurrencyLimiter(), semalib.PriorityBatch))
+func NewConcurrencyLimit(l *semalib.Limiter, priority ...semalib.Priority) yago.HttpHandlerFunc {
+	defaultPriority := semalib.PriorityNormal
+	if len(priority) > 0 {
+		defaultPriority = priority[0]
+	}
+
+	return func(c *yago.Ctx) {
+		p := defaultPriority
+		if r, ok := c.GetHttpRouter(); ok {
+			if v, ok := r.Metadata.(ConcurrencyPrioritizer); ok {
+				p = v.ConcurrencyPriority()
+			}
+		}
+
+		release, err := l.Acquire(c.Request.Context(), p)
+		if errors.Is(err, semalib.ErrLimitExceeded) {
+			c.AbortWithE(yago.ErrOverloaded)
+			return
+		}
+		if err != nil {
+			c.AbortWithE(err)
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
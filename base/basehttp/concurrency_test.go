@@ -0,0 +1,52 @@
+package basehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/libs/semalib"
+)
+
+// go test -v ./base/basehttp -test.run TestConcurrencyLimit
+
+func TestConcurrencyLimit(t *testing.T) {
+	l := semalib.NewLimiter(semalib.LimiterConfig{Limit: 1, MaxWait: 100 * time.Millisecond})
+	release, err := l.Acquire(context.Background(), semalib.PriorityCritical)
+	if err != nil {
+		t.Fatalf("acquire err: %s", err)
+	}
+	defer release()
+
+	e := gin.New()
+	e.GET("/", ginHandler(NewConcurrencyLimit(l, semalib.PriorityCritical)), ginHandler(func(c *yago.Ctx) {
+		c.SetData("ok")
+	}))
+	e.GET("/timeout", ginHandler(NewTimeout(20*time.Millisecond)), ginHandler(NewConcurrencyLimit(l, semalib.PriorityCritical)), ginHandler(func(c *yago.Ctx) {
+		c.SetData("ok")
+	}))
+
+	cases := []struct {
+		name  string
+		path  string
+		errno string
+	}{
+		// 等待 MaxWait 后仍超出上限
+		{"overloaded", "/", `"errno":11,`},
+		// 等待中请求自己的 deadline 先到, 返回超时而不是过载
+		{"caller deadline", "/timeout", `"errno":13,`},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if !strings.Contains(w.Body.String(), tc.errno) {
+			t.Errorf("%s got %s, want %s", tc.name, w.Body.String(), tc.errno)
+		}
+	}
+}
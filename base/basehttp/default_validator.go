@@ -43,8 +43,11 @@ func (v *defaultValidator) Engine() interface{} {
 
 func (v *defaultValidator) lazyinit() {
 	v.once.Do(func() {
-		trans := yago.GetTranslator()
-		v.validate = validatelib.Ins(trans)
+		// 按请求语言翻译校验错误, 所有语言的翻译都注册在同一个 validator 上
+		v.validate = validatelib.Ins(nil)
+		for _, trans := range yago.GetTranslators() {
+			validatelib.RegisterTranslations(v.validate, trans)
+		}
 
 		v.validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
 			name := strings.SplitN(fld.Tag.Get("label"), ",", 2)[0]
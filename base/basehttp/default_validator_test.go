@@ -0,0 +1,45 @@
+package basehttp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hulklab/yago"
+)
+
+// go test -v ./base/basehttp -test.run TestDefaultValidator
+
+func TestDefaultValidator_Translations(t *testing.T) {
+	v := &defaultValidator{}
+
+	req := struct {
+		Name  string `validate:"required" label:"name"`
+		Phone string `validate:"phone" label:"phone"`
+	}{Phone: "123"}
+
+	var ves validator.ValidationErrors
+	if err := v.ValidateStruct(&req); !errors.As(err, &ves) || len(ves) != 2 {
+		t.Fatalf("validate got %v", err)
+	}
+
+	// 每个语言都注册了内置和自定义校验的翻译
+	cases := []struct {
+		lang  string
+		name  string
+		phone string
+	}{
+		{"zh", "name为必填字段", "phone 必须是一个有效的手机号"},
+		{"en", "name is a required field", "phone must be a valid phone number"},
+	}
+
+	for _, c := range cases {
+		trans := yago.GetTranslator(c.lang)
+		if got := ves[0].Translate(trans); got != c.name {
+			t.Errorf("%s required got %q, want %q", c.lang, got, c.name)
+		}
+		if got := ves[1].Translate(trans); got != c.phone {
+			t.Errorf("%s phone got %q, want %q", c.lang, got, c.phone)
+		}
+	}
+}
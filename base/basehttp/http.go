@@ -1,11 +1,60 @@
 package basehttp
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin/binding"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+	"github.com/sirupsen/logrus"
 )
 
 type BaseHttp struct{}
 
 func init() {
 	binding.Validator = &defaultValidator{}
+
+	yago.SetHttpPanicLogger(logPanic)
+
+	yago.AddAppInitHook(func(app *yago.App) error {
+		if yago.Config.GetBool("app.http_access_log_on") {
+			yago.HttpDefaultMiddleware().Use(NewAccessLog(loadAccessLogConfig()))
+		}
+		if yago.Config.GetBool("app.http_ratelimit_on") {
+			yago.HttpDefaultMiddleware().Use(newRouteRateLimit("ratelimit", true))
+		}
+		// 在并发限制之前, 等待并发许可的时间也计入超时
+		if yago.Config.GetBool("app.http_timeout_on") {
+			// yago.Ctx 作为 context 时使用 request 的 context, 直接传 c 也能带上 deadline
+			if e := app.HttpEngine(); e != nil {
+				e.ContextWithFallback = true
+			}
+			yago.HttpDefaultMiddleware().Use(NewRouteTimeout("http_timeout"))
+		}
+		if yago.Config.GetBool("app.http_concurrency_limit_on") {
+			yago.HttpDefaultMiddleware().Use(NewConcurrencyLimit(yago.ConcurrencyLimiter()))
+		}
+		if yago.Config.GetBool("app.http_idempotency_on") {
+			yago.HttpDefaultMiddleware().Use(newRouteIdempotency("idempotency", true))
+		}
+		if yago.Config.GetBool("app.http_cache_on") {
+			yago.HttpDefaultMiddleware().Use(newRouteResponseCache("http_cache", true))
+		}
+		return nil
+	})
+}
+
+func logPanic(c *yago.Ctx, p interface{}, stack []byte) {
+	logInfo := logrus.Fields{
+		"category":   "http.server",
+		"method":     c.Request.Method,
+		"route":      c.FullPath(),
+		"uri":        c.Request.URL.RequestURI(),
+		"ip":         c.ClientIP(),
+		"request_id": c.GetRequestId(),
+		"hint":       fmt.Sprintf("panic: %v", p),
+		"stack":      string(stack),
+	}
+
+	logger.Ins().WithContext(c.Request.Context()).WithFields(logInfo).Error()
 }
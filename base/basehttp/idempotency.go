@@ -0,0 +1,407 @@
+package basehttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+	"github.com/hulklab/yago/coms/rds"
+	"github.com/hulklab/yago/libs/str"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// 回放保存的响应时写入该响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// 保存请求的处理状态和最终响应
+type IdempotencyStore interface {
+	// key 不存在时写入并返回 true
+	SetNx(key string, value []byte, ttl time.Duration) (bool, error)
+	// key 不存在时返回 nil, nil
+	Get(key string) ([]byte, error)
+	// 当前值等于 old 时写入并返回 true, 锁过期后被其它请求拿到时不覆盖
+	CompareAndSet(key string, old, value []byte, ttl time.Duration) (bool, error)
+	// 当前值等于 old 时删除并返回 true
+	CompareAndDel(key string, old []byte) (bool, error)
+}
+
+type redisIdempotencyStore struct {
+	rdsId string
+}
+
+func NewRedisIdempotencyStore(rdsId string) IdempotencyStore {
+	return &redisIdempotencyStore{rdsId: rdsId}
+}
+
+func (s *redisIdempotencyStore) SetNx(key string, value []byte, ttl time.Duration) (bool, error) {
+	_, err := redis.String(rds.Ins(s.rdsId).Set(key, value, "PX", ttl.Milliseconds(), "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *redisIdempotencyStore) Get(key string) ([]byte, error) {
+	b, err := redis.Bytes(rds.Ins(s.rdsId).Get(key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return b, err
+}
+
+// KEYS[1] key, ARGV: 旧值, 新值, 毫秒数
+var idempotencyCompareAndSetScript = redis.NewScript(1, `
+	if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+		return 0
+	end
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+`)
+
+// KEYS[1] key, ARGV[1] 旧值
+var idempotencyCompareAndDelScript = redis.NewScript(1, `
+	if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+		return 0
+	end
+	return redis.call("DEL", KEYS[1])
+`)
+
+func (s *redisIdempotencyStore) CompareAndSet(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	rc := rds.Ins(s.rdsId).GetConn()
+	defer rc.Close()
+
+	return redis.Bool(idempotencyCompareAndSetScript.Do(rc, key, old, value, ttl.Milliseconds()))
+}
+
+func (s *redisIdempotencyStore) CompareAndDel(key string, old []byte) (bool, error) {
+	rc := rds.Ins(s.rdsId).GetConn()
+	defer rc.Close()
+
+	return redis.Bool(idempotencyCompareAndDelScript.Do(rc, key, old))
+}
+
+// 区分不同调用方的 key, 避免不同用户使用相同的 Idempotency-Key 时互相回放
+type IdempotencyScopeFunc func(c *yago.Ctx) string
+
+// 已登录用户按用户, 签名调用按 app key, 都没有时为空
+// 需要在 auth 或签名校验中间件之后使用
+func IdempotencyScopeByCaller(c *yago.Ctx) string {
+	if key := RateLimitByUser(c); key != "" {
+		return key
+	}
+	return RateLimitByAppKey(c)
+}
+
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+	// key 前缀
+	Prefix string
+	// 最终响应保存的时间, 时间内的重试直接回放
+	TTL time.Duration
+	// 处理中的锁的过期时间, 避免进程退出后 key 一直处于处理中
+	LockTimeout time.Duration
+	// 没有 Idempotency-Key 时返回 ErrParam, 否则直接放行
+	Required bool
+	// 参与请求指纹的 body 最大字节数, 包括 multipart 上传, 超出时返回 ErrParam
+	MaxBodySize int64
+	// 为 nil 时使用 IdempotencyScopeByCaller
+	Scope IdempotencyScopeFunc
+	// scope 为空时所有调用方共享 key, 否则返回 ErrAuth, 只用于不需要登录的接口
+	AllowAnonymous bool
+}
+
+// 从配置段加载
+//
+//	[idempotency]
+//	redis_instance_id = "redis"
+//	ttl = "24h"
+//	routes = ["POST /order/create"]
+func LoadIdempotencyConfig(section string) *IdempotencyConfig {
+	rdsId := yago.Config.GetString(section + ".redis_instance_id")
+	if rdsId == "" {
+		rdsId = "redis"
+	}
+
+	conf := &IdempotencyConfig{
+		Store:          NewRedisIdempotencyStore(rdsId),
+		Prefix:         yago.Config.GetString(section + ".prefix"),
+		TTL:            yago.Config.GetDuration(section + ".ttl"),
+		LockTimeout:    yago.Config.GetDuration(section + ".lock_timeout"),
+		Required:       yago.Config.GetBool(section + ".required"),
+		MaxBodySize:    yago.Config.GetInt64(section + ".max_body_size"),
+		AllowAnonymous: yago.Config.GetBool(section + ".allow_anonymous"),
+	}
+
+	return conf
+}
+
+func (conf *IdempotencyConfig) init() {
+	if conf.Store == nil {
+		log.Fatalf("Fatal error: idempotency store is required")
+	}
+	if conf.Prefix == "" {
+		conf.Prefix = "idempotency:"
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = 24 * time.Hour
+	}
+	if conf.LockTimeout <= 0 {
+		conf.LockTimeout = time.Minute
+	}
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = 10 << 20
+	}
+	if conf.Scope == nil {
+		conf.Scope = IdempotencyScopeByCaller
+	}
+}
+
+// 对带 Idempotency-Key 的 POST, PUT, PATCH, DELETE 请求, 保存最终响应, TTL 内的重试直接回放
+// 相同的 key 正在处理时返回 ErrConflict, 请求参数不一致时返回 ErrParam, 取不到调用方时返回 ErrAuth
+// 5xx 的响应和 handler panic 时不保存, 可以重试
+// eg. ghttp.Root.Post("/order/create", basehttp.NewIdempotency(basehttp.LoadIdempotencyConfig("idempotency")), h.CreateAction)
+func NewIdempotency(conf *IdempotencyConfig) yago.HttpHandlerFunc {
+	conf.init()
+
+	return func(c *yago.Ctx) {
+		idempotency(c, conf)
+	}
+}
+
+// 按配置段中的 routes 使用, 需要在 auth 或签名校验中间件之后使用, 按调用方区分 key
+// eg. yago.NewHttpGroupRouter("/api", auth.Ins().Middleware(), basehttp.NewRouteIdempotency("idempotency"))
+func NewRouteIdempotency(section string) yago.HttpHandlerFunc {
+	return newRouteIdempotency(section, false)
+}
+
+// app.http_idempotency_on 开启后作为默认中间件, 默认中间件在 auth 之前执行, 取不到调用方,
+// 所有调用方共享 key, 只能用于不需要登录的接口, 需要配置 allow_anonymous 明确声明
+func newRouteIdempotency(section string, defaultMiddleware bool) yago.HttpHandlerFunc {
+	conf := LoadIdempotencyConfig(section)
+	conf.init()
+
+	if defaultMiddleware && !conf.AllowAnonymous {
+		log.Fatalf("Fatal error: app.http_idempotency_on runs before auth and shares keys between callers, "+
+			"set %s.allow_anonymous only if the routes are anonymous, or use basehttp.NewRouteIdempotency after auth middleware", section)
+	}
+
+	// 路由模板或 "METHOD 路由模板"
+	routes := make(map[string]struct{})
+	for _, r := range yago.Config.GetStringSlice(section + ".routes") {
+		route := strings.Fields(r)
+		if len(route) == 2 {
+			r = strings.ToUpper(route[0]) + " " + route[1]
+		}
+		routes[r] = struct{}{}
+	}
+
+	return func(c *yago.Ctx) {
+		_, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			_, ok = routes[c.FullPath()]
+		}
+		if !ok {
+			return
+		}
+
+		idempotency(c, conf)
+	}
+}
+
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	// 处理中的锁的标识, 只释放自己加的锁
+	Token string `json:"token,omitempty"`
+	// 为 0 时请求正在处理
+	Status int             `json:"status,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type idempotencyBody struct {
+	ErrNo   int                    `json:"errno"`
+	ErrMsg  string                 `json:"errmsg"`
+	Data    json.RawMessage        `json:"data,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func idempotency(c *yago.Ctx, conf *IdempotencyConfig) {
+	switch c.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return
+	}
+
+	idemKey := c.GetHeader(IdempotencyKeyHeader)
+	if idemKey == "" {
+		if conf.Required {
+			c.AbortWithE(yago.NewErr(yago.ErrParam, IdempotencyKeyHeader+" header is required"))
+		}
+		return
+	}
+	if len(idemKey) > 255 {
+		c.AbortWithE(yago.NewErr(yago.ErrParam, IdempotencyKeyHeader+" header is too long"))
+		return
+	}
+
+	fingerprint, err := idempotencyFingerprint(c, conf.MaxBodySize)
+	if err != nil {
+		c.AbortWithE(yago.NewErr(yago.ErrParam, err))
+		return
+	}
+
+	// 不同调用方不能共享 key, 否则可能回放其他用户的响应
+	scope := conf.Scope(c)
+	if scope == "" && !conf.AllowAnonymous {
+		c.AbortWithE(yago.NewErr(yago.ErrAuth, IdempotencyKeyHeader+" requires a login user or signed app key"))
+		return
+	}
+
+	key := conf.Prefix + c.Request.Method + ":" + c.FullPath() + ":" + scope + ":" + idemKey
+
+	lock, _ := json.Marshal(&idempotencyRecord{Fingerprint: fingerprint, Token: str.UniqueId()})
+	ok, err := conf.Store.SetNx(key, lock, conf.LockTimeout)
+	if err != nil {
+		logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Errorf("lock %s err: %s", key, err)
+		c.AbortWithE(yago.ErrSystem)
+		return
+	}
+
+	if !ok {
+		replayIdempotency(c, conf, key, fingerprint)
+		return
+	}
+
+	// handler panic 或者响应不保存时释放锁, 客户端可以用相同的 key 重试
+	saved := false
+	defer func() {
+		if saved {
+			return
+		}
+		if _, err := conf.Store.CompareAndDel(key, lock); err != nil {
+			logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Errorf("unlock %s err: %s", key, err)
+		}
+	}()
+
+	c.Next()
+
+	resp, ok := c.GetResponse()
+	status := c.Writer.Status()
+	if !ok || !idempotencyStorable(status, resp) {
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err == nil {
+		var record []byte
+		record, err = json.Marshal(&idempotencyRecord{Fingerprint: fingerprint, Status: status, Body: body})
+		if err == nil {
+			saved, err = conf.Store.CompareAndSet(key, lock, record, conf.TTL)
+		}
+	}
+	if err != nil {
+		logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Errorf("save response of %s err: %s", key, err)
+	} else if !saved {
+		logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Warnf("lock of %s expired before response saved", key)
+	}
+}
+
+func replayIdempotency(c *yago.Ctx, conf *IdempotencyConfig, key, fingerprint string) {
+	b, err := conf.Store.Get(key)
+	if err != nil {
+		logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Errorf("get %s err: %s", key, err)
+		c.AbortWithE(yago.ErrSystem)
+		return
+	}
+
+	// 锁刚好过期或者被释放, 让客户端重试
+	if b == nil {
+		c.AbortWithE(yago.ErrConflict)
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(b, &record); err != nil {
+		logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Errorf("decode %s err: %s", key, err)
+		c.AbortWithE(yago.ErrSystem)
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.AbortWithE(yago.NewErr(yago.ErrParam, IdempotencyKeyHeader+" is reused with different request"))
+		return
+	}
+
+	if record.Status == 0 {
+		c.AbortWithE(yago.ErrConflict)
+		return
+	}
+
+	var body idempotencyBody
+	if err := json.Unmarshal(record.Body, &body); err != nil {
+		logger.Ins().Category("http.idempotency").WithContext(c.Request.Context()).Errorf("decode body of %s err: %s", key, err)
+		c.AbortWithE(yago.ErrSystem)
+		return
+	}
+
+	resp := &yago.ResponseBody{
+		ErrNo:   body.ErrNo,
+		ErrMsg:  body.ErrMsg,
+		Details: body.Details,
+	}
+	if len(body.Data) > 0 {
+		resp.Data = body.Data
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.SetResponse(record.Status, resp)
+	c.Abort()
+}
+
+// 服务端错误不保存, 客户端可以用相同的 key 重试
+func idempotencyStorable(status int, resp *yago.ResponseBody) bool {
+	if status >= http.StatusInternalServerError {
+		return false
+	}
+
+	if ec, ok := yago.GetErrCode(resp.ErrNo); ok && ec.HttpStatus >= http.StatusInternalServerError {
+		return false
+	}
+
+	return true
+}
+
+// 请求方法, 路径, 参数和 body 的摘要, 相同的 key 只能用于相同的请求
+func idempotencyFingerprint(c *yago.Ctx, maxBodySize int64) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + "\n" + c.Request.URL.Path + "\n" + c.Request.URL.Query().Encode() + "\n"))
+
+	if c.Request.Body != nil {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		// multipart 的 boundary 每次请求都可能不同, 去掉后再摘要
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			if _, params, err := mime.ParseMediaType(c.GetHeader("Content-Type")); err == nil && params["boundary"] != "" {
+				body = bytes.ReplaceAll(body, []byte(params["boundary"]), nil)
+			}
+		}
+		h.Write(body)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,267 @@
+package basehttp
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/auth"
+)
+
+// go test -v ./base/basehttp -test.run TestIdempotency
+
+type memoryIdempotencyStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{values: make(map[string][]byte)}
+}
+
+func (s *memoryIdempotencyStore) SetNx(key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.values[key] = value
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.values[key], nil
+}
+
+func (s *memoryIdempotencyStore) CompareAndSet(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !bytes.Equal(s.values[key], old) {
+		return false, nil
+	}
+	s.values[key] = value
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) CompareAndDel(key string, old []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !bytes.Equal(s.values[key], old) {
+		return false, nil
+	}
+	delete(s.values, key)
+	return true, nil
+}
+
+type idempotencyTestServer struct {
+	engine *gin.Engine
+	store  *memoryIdempotencyStore
+	calls  int
+	// handler 执行时调用
+	during func(c *yago.Ctx)
+}
+
+// /order 返回调用次数, 带 user header 时设置登录用户, panic=1 时 panic, err=1 时返回 5xx
+func newIdempotencyTestServer(conf *IdempotencyConfig) *idempotencyTestServer {
+	s := &idempotencyTestServer{engine: gin.New(), store: newMemoryIdempotencyStore()}
+	conf.Store = s.store
+
+	setUser := func(c *yago.Ctx) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			auth.SetPrincipal(c, &auth.Principal{Subject: user})
+		}
+	}
+
+	s.engine.POST("/order",
+		func(c *gin.Context) {
+			defer func() {
+				if recover() != nil {
+					c.AbortWithStatus(http.StatusInternalServerError)
+				}
+			}()
+			c.Next()
+		},
+		ginHandler(setUser),
+		ginHandler(NewIdempotency(conf)),
+		ginHandler(func(c *yago.Ctx) {
+			s.calls++
+			if s.during != nil {
+				s.during(c)
+			}
+			if c.Query("panic") != "" {
+				panic("test panic")
+			}
+			if c.Query("err") != "" {
+				c.SetError(yago.ErrSystem)
+				return
+			}
+			c.SetData(map[string]interface{}{"calls": s.calls})
+		}),
+	)
+
+	return s
+}
+
+func (s *idempotencyTestServer) post(target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+func idempotencyHeaders(user, key string) map[string]string {
+	return map[string]string{"X-Test-User": user, IdempotencyKeyHeader: key}
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	s := newIdempotencyTestServer(&IdempotencyConfig{})
+	alice := idempotencyHeaders("alice", "k1")
+
+	first := s.post("/order", `{"a":1}`, alice)
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatal("first request should not be replayed")
+	}
+
+	w := s.post("/order", `{"a":1}`, alice)
+	if w.Header().Get(IdempotentReplayedHeader) != "true" || w.Body.String() != first.Body.String() {
+		t.Errorf("retry should replay, got %v %s", w.Header(), w.Body.String())
+	}
+
+	if w := s.post("/order", `{"a":2}`, alice); !strings.Contains(w.Body.String(), `"errno":2`) {
+		t.Errorf("reused key with different body got %s", w.Body.String())
+	}
+
+	// 不同用户的相同 key 互不影响
+	if w := s.post("/order", `{"a":1}`, idempotencyHeaders("bob", "k1")); w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("key should not be shared between users")
+	}
+
+	if s.calls != 2 {
+		t.Errorf("handler should be called twice, got %d", s.calls)
+	}
+}
+
+// 每次请求的 boundary 不同, 只有上传的内容参与摘要
+func TestIdempotency_Multipart(t *testing.T) {
+	s := newIdempotencyTestServer(&IdempotencyConfig{})
+
+	upload := func(content string) (string, map[string]string) {
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		fw, _ := mw.CreateFormFile("file", "a.txt")
+		_, _ = fw.Write([]byte(content))
+		_ = mw.Close()
+
+		headers := idempotencyHeaders("alice", "k1")
+		headers["Content-Type"] = mw.FormDataContentType()
+		return b.String(), headers
+	}
+
+	body, headers := upload("first")
+	first := s.post("/order", body, headers)
+
+	body, headers = upload("first")
+	if w := s.post("/order", body, headers); w.Header().Get(IdempotentReplayedHeader) != "true" || w.Body.String() != first.Body.String() {
+		t.Errorf("retry with same upload should replay, got %s", w.Body.String())
+	}
+
+	body, headers = upload("second")
+	if w := s.post("/order", body, headers); !strings.Contains(w.Body.String(), `"errno":2`) {
+		t.Errorf("reused key with different upload got %s", w.Body.String())
+	}
+
+	if s.calls != 1 {
+		t.Errorf("handler should be called once, got %d", s.calls)
+	}
+}
+
+func TestIdempotency_Scope(t *testing.T) {
+	s := newIdempotencyTestServer(&IdempotencyConfig{})
+
+	anonymous := map[string]string{IdempotencyKeyHeader: "k1"}
+	if w := s.post("/order", `{}`, anonymous); !strings.Contains(w.Body.String(), `"errno":4`) || s.calls != 0 {
+		t.Errorf("anonymous request should be rejected, got %s", w.Body.String())
+	}
+
+	// 没有 key 时不处理
+	if w := s.post("/order", `{}`, nil); !strings.Contains(w.Body.String(), `"errno":0`) {
+		t.Errorf("request without key got %s", w.Body.String())
+	}
+
+	s = newIdempotencyTestServer(&IdempotencyConfig{AllowAnonymous: true})
+	s.post("/order", `{}`, anonymous)
+	if w := s.post("/order", `{}`, anonymous); w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("anonymous request should be replayed when allowed")
+	}
+}
+
+func TestIdempotency_Unlock(t *testing.T) {
+	s := newIdempotencyTestServer(&IdempotencyConfig{})
+	alice := idempotencyHeaders("alice", "k1")
+
+	// 处理中返回 conflict
+	s.during = func(c *yago.Ctx) {
+		if w := s.post("/order", `{}`, alice); !strings.Contains(w.Body.String(), `"errno":12`) {
+			t.Errorf("request in process should conflict, got %s", w.Body.String())
+		}
+	}
+	s.post("/order", `{}`, alice)
+	s.during = nil
+
+	// panic 和 5xx 时释放锁, 可以重试
+	for _, target := range []string{"/order?panic=1", "/order?err=1"} {
+		key := idempotencyHeaders("alice", target)
+		s.post(target, `{}`, key)
+		if len(s.store.values) != 1 {
+			t.Errorf("%s should unlock, got %d keys", target, len(s.store.values))
+		}
+
+		calls := s.calls
+		s.post(target, `{}`, key)
+		if s.calls != calls+1 {
+			t.Errorf("%s should be retried", target)
+		}
+	}
+
+	// 锁过期后被其它请求拿到时, 不删除也不覆盖其它请求的锁
+	other := []byte(`{"fingerprint":"other","token":"other"}`)
+	s.during = func(c *yago.Ctx) {
+		for k := range s.store.values {
+			if strings.HasSuffix(k, ":k2") || strings.HasSuffix(k, ":k3") {
+				s.store.values[k] = other
+			}
+		}
+	}
+	s.post("/order", `{}`, idempotencyHeaders("alice", "k2"))
+	s.post("/order?err=1", `{}`, idempotencyHeaders("alice", "k3"))
+
+	for _, suffix := range []string{":k2", ":k3"} {
+		var found bool
+		for k, v := range s.store.values {
+			if strings.HasSuffix(k, suffix) {
+				found = bytes.Equal(v, other)
+			}
+		}
+		if !found {
+			t.Errorf("lock of other request %s should be kept", suffix)
+		}
+	}
+}
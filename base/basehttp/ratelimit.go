@@ -0,0 +1,193 @@
+package basehttp
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/auth"
+	"github.com/hulklab/yago/coms/logger"
+)
+
+// 从请求中取限流的 key, 返回空字符串时记录日志并放行, 不会改为按 ip 限流
+type RateLimitKeyFunc func(c *yago.Ctx) string
+
+func RateLimitByIP(c *yago.Ctx) string {
+	return "ip:" + c.ClientIP()
+}
+
+// 需要在 auth 中间件之后使用
+func RateLimitByUser(c *yago.Ctx) string {
+	if p, ok := auth.GetPrincipal(c); ok {
+		return "user:" + p.Subject
+	}
+	return ""
+}
+
+// 需要在签名校验中间件之后使用
+func RateLimitByAppKey(c *yago.Ctx) string {
+	if appKey := c.GetString(SignedAppKey); appKey != "" {
+		return "app:" + appKey
+	}
+	return ""
+}
+
+// 需要在 auth 或签名校验中间件之后才能取到的 key, 不能用于默认中间件
+var rateLimitAuthKeys = map[string]bool{
+	"user":    true,
+	"app_key": true,
+}
+
+var rateLimitKeyFuncs = map[string]RateLimitKeyFunc{
+	"ip":      RateLimitByIP,
+	"user":    RateLimitByUser,
+	"app_key": RateLimitByAppKey,
+}
+
+// 注册自定义的 key, 可以在限流配置中使用
+// eg. basehttp.RegisterRateLimitKeyFunc("device", func(c *yago.Ctx) string { return c.GetHeader("X-Device-Id") })
+func RegisterRateLimitKeyFunc(name string, f RateLimitKeyFunc) {
+	rateLimitKeyFuncs[name] = f
+}
+
+type RateLimitRule struct {
+	// 路由模板, 可以带请求方法, eg. "POST /user/login", "/sms/send"
+	Route  string        `mapstructure:"route"`
+	Limit  int           `mapstructure:"limit"`
+	Window time.Duration `mapstructure:"window"`
+	// ip, user, app_key 或 RegisterRateLimitKeyFunc 注册的名字, 默认 ip
+	Key string `mapstructure:"key"`
+}
+
+var (
+	defaultRateLimiter     RateLimiter
+	defaultRateLimiterOnce sync.Once
+)
+
+// 按 ratelimit 配置段创建, 未配置时使用单机令牌桶
+func DefaultRateLimiter() RateLimiter {
+	defaultRateLimiterOnce.Do(func() {
+		defaultRateLimiter = newRateLimiterFromConfig("ratelimit")
+	})
+	return defaultRateLimiter
+}
+
+func newRateLimiterFromConfig(section string) RateLimiter {
+	switch driver := yago.Config.GetString(section + ".driver"); driver {
+	case "memory", "":
+		return NewMemoryRateLimiter()
+	case "redis":
+		rdsId := yago.Config.GetString(section + ".redis_instance_id")
+		if rdsId == "" {
+			rdsId = "redis"
+		}
+		prefix := yago.Config.GetString(section + ".prefix")
+		if prefix == "" {
+			prefix = "ratelimit:"
+		}
+		return NewRedisRateLimiter(rdsId, prefix)
+	default:
+		log.Fatalf("Fatal error: unsupported ratelimit driver %s", driver)
+	}
+	return nil
+}
+
+// 在路由组或单个路由上使用, name 区分不同的限流额度, limiter 为空时使用 DefaultRateLimiter
+// eg. ghttp.Root.Post("/sms/send", basehttp.NewRateLimit("sms", basehttp.RateLimit{Limit: 1, Window: time.Minute}, basehttp.RateLimitByUser), h.SendAction)
+func NewRateLimit(name string, rl RateLimit, keyFunc RateLimitKeyFunc, limiter ...RateLimiter) yago.HttpHandlerFunc {
+	var l RateLimiter
+	if len(limiter) > 0 {
+		l = limiter[0]
+	}
+
+	return func(c *yago.Ctx) {
+		if l == nil {
+			rateLimit(c, DefaultRateLimiter(), name, rl, keyFunc)
+			return
+		}
+		rateLimit(c, l, name, rl, keyFunc)
+	}
+}
+
+// 按配置段中的 routes 对路由限流, 按用户或 app_key 限流时需要在 auth 或签名校验中间件之后使用
+// eg. yago.NewHttpGroupRouter("/api", auth.Ins().Middleware(), basehttp.NewRouteRateLimit("ratelimit"))
+func NewRouteRateLimit(section string) yago.HttpHandlerFunc {
+	return newRouteRateLimit(section, false)
+}
+
+// app.http_ratelimit_on 开启后作为默认中间件, 默认中间件在 auth 之前执行, 不能按用户或 app_key 限流
+func newRouteRateLimit(section string, defaultMiddleware bool) yago.HttpHandlerFunc {
+	rules := make([]RateLimitRule, 0)
+	if err := yago.Config.UnmarshalKey(section+".routes", &rules); err != nil {
+		log.Fatalf("Fatal error: parse %s.routes err: %s", section, err)
+	}
+
+	// 路由模板或 "METHOD 路由模板" => rule
+	routes := make(map[string]RateLimitRule, len(rules))
+	for _, r := range rules {
+		if r.Limit <= 0 || r.Window <= 0 {
+			log.Fatalf("Fatal error: ratelimit route %s limit and window must be positive", r.Route)
+		}
+
+		if r.Key == "" {
+			r.Key = "ip"
+		}
+		if _, ok := rateLimitKeyFuncs[r.Key]; !ok {
+			log.Fatalf("Fatal error: ratelimit route %s has unknown key %s", r.Route, r.Key)
+		}
+		if rateLimitAuthKeys[r.Key] && defaultMiddleware {
+			log.Fatalf("Fatal error: ratelimit route %s with key %s can not be used by app.http_ratelimit_on, use basehttp.NewRouteRateLimit after auth or sign middleware", r.Route, r.Key)
+		}
+
+		route := strings.Fields(r.Route)
+		if len(route) == 2 {
+			r.Route = strings.ToUpper(route[0]) + " " + route[1]
+		}
+		routes[r.Route] = r
+	}
+
+	return func(c *yago.Ctx) {
+		r, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			r, ok = routes[c.FullPath()]
+		}
+		if !ok {
+			return
+		}
+
+		rateLimit(c, DefaultRateLimiter(), r.Route, RateLimit{Limit: r.Limit, Window: r.Window}, rateLimitKeyFuncs[r.Key])
+	}
+}
+
+// 写入 RateLimit-* 响应头, 超出限制时返回 ErrTooManyRequests, 取不到 key 或限流器出错时放行
+func rateLimit(c *yago.Ctx, limiter RateLimiter, name string, rl RateLimit, keyFunc RateLimitKeyFunc) {
+	key := keyFunc(c)
+	if key == "" {
+		// 通常是限流中间件在 auth 或签名校验之前执行, 按 ip 限流会让同一出口的调用方共享额度
+		logger.Ins().Category("http.ratelimit").WithContext(c.Request.Context()).Errorf("%s rate limit key is empty, %s %s", name, c.Request.Method, c.FullPath())
+		return
+	}
+
+	res, err := limiter.Allow(name+":"+key, rl)
+	if err != nil {
+		logger.Ins().Category("http.ratelimit").WithContext(c.Request.Context()).Errorf("%s rate limit err: %s", name, err)
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+
+	if !res.Allowed {
+		c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+		c.AbortWithE(yago.ErrTooManyRequests)
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
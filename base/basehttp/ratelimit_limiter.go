@@ -0,0 +1,182 @@
+package basehttp
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/hulklab/yago/coms/rds"
+	"github.com/hulklab/yago/libs/str"
+)
+
+// 窗口内最多允许 Limit 个请求
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// 额度完全恢复到 Limit 需要的时间, 期间没有新请求, 单机和 redis 限流器含义相同
+	// 单机令牌桶为补满的时间, redis 滑动窗口为最新的请求离开窗口的时间, 即 RateLimit-Reset 响应头
+	Reset time.Duration
+	// 被拒绝时, 下一个请求可以通过需要等待的时间
+	RetryAfter time.Duration
+}
+
+type RateLimiter interface {
+	Allow(key string, rl RateLimit) (*RateLimitResult, error)
+}
+
+// 清理空闲 bucket 的间隔
+const memoryRateLimiterSweepInterval = time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// 补满的时间, 不同规则的窗口不同
+	window time.Duration
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// 上次清理空闲 bucket 的时间
+	swept time.Time
+}
+
+// 单机令牌桶, 容量为 Limit, 每个 Window 补满
+func NewMemoryRateLimiter() RateLimiter {
+	return &memoryRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		swept:   time.Now(),
+	}
+}
+
+func (l *memoryRateLimiter) Allow(key string, rl RateLimit) (*RateLimitResult, error) {
+	now := time.Now()
+	capacity := float64(rl.Limit)
+	// 每纳秒补充的令牌数
+	rate := capacity / float64(rl.Window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.window = rl.Window
+
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))*rate)
+	b.last = now
+
+	res := &RateLimitResult{Limit: rl.Limit}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((capacity - b.tokens) / rate)
+
+	return res, nil
+}
+
+// 定时清理已经补满的 bucket, 补满的 bucket 与新建的没有区别
+// 按 bucket 自己的窗口判断, 短窗口的规则不会清理长窗口规则未补满的 bucket
+func (l *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < memoryRateLimiterSweepInterval {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= b.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type redisRateLimiter struct {
+	rdsId  string
+	prefix string
+}
+
+// 基于 redis 有序集合的滑动窗口, 多个实例共享限流额度
+func NewRedisRateLimiter(rdsId, prefix string) RateLimiter {
+	return &redisRateLimiter{rdsId: rdsId, prefix: prefix}
+}
+
+// KEYS[1] 限流 key, ARGV: 窗口毫秒数, 限制次数, 请求标识
+// 使用 redis 的时间, 各实例的时钟偏差不影响窗口
+// 返回 {是否通过, 剩余次数, 最新的请求离开窗口的毫秒数, 最早的请求离开窗口的毫秒数}
+var slidingWindowScript = redis.NewScript(1, `
+	-- redis 5 以下调用 TIME 后写入需要按命令复制
+	redis.replicate_commands()
+
+	local t = redis.call("TIME")
+	local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+	local window = tonumber(ARGV[1])
+	local limit = tonumber(ARGV[2])
+
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+	local count = redis.call("ZCARD", KEYS[1])
+	local allowed = 0
+	if count < limit then
+		redis.call("ZADD", KEYS[1], now, ARGV[3])
+		count = count + 1
+		allowed = 1
+	end
+	redis.call("PEXPIRE", KEYS[1], window)
+
+	local reset = 0
+	local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	if newest[2] then
+		reset = tonumber(newest[2]) + window - now
+	end
+
+	local retry = 0
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	if oldest[2] then
+		retry = tonumber(oldest[2]) + window - now
+	end
+
+	return {allowed, limit - count, reset, retry}
+`)
+
+func (l *redisRateLimiter) Allow(key string, rl RateLimit) (*RateLimitResult, error) {
+	window := rl.Window.Milliseconds()
+	member := str.UniqueId()
+
+	rc := rds.Ins(l.rdsId).GetConn()
+	defer rc.Close()
+
+	values, err := redis.Int64s(slidingWindowScript.Do(rc, l.prefix+key, window, rl.Limit, member))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     rl.Limit,
+		Remaining: int(values[1]),
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}
+
+	if !res.Allowed {
+		res.RetryAfter = time.Duration(values[3]) * time.Millisecond
+	}
+
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package basehttp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/rds"
+	"github.com/hulklab/yago/libs/str"
+)
+
+// go test -v ./base/basehttp -test.run TestRateLimiter
+// redis 限流需要配置: go test -v ./base/basehttp -test.run TestRedisRateLimiter -args "-c=${PWD}/app.toml"
+
+func testRateLimiter(t *testing.T, l RateLimiter) {
+	key := "test:" + str.UniqueId()
+	rl := RateLimit{Limit: 3, Window: 200 * time.Millisecond}
+
+	for i := 0; i < rl.Limit; i++ {
+		res, err := l.Allow(key, rl)
+		if err != nil {
+			t.Fatal("allow err:", err)
+		}
+		if !res.Allowed || res.Remaining != rl.Limit-i-1 {
+			t.Fatalf("request %d got allowed %v remaining %d", i, res.Allowed, res.Remaining)
+		}
+	}
+
+	res, err := l.Allow(key, rl)
+	if err != nil {
+		t.Fatal("allow err:", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("request over limit got allowed %v remaining %d", res.Allowed, res.Remaining)
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > rl.Window {
+		t.Errorf("retry after got %s", res.RetryAfter)
+	}
+	// Reset 为额度完全恢复的时间, 不早于下一个请求可以通过的时间
+	if res.Reset < res.RetryAfter || res.Reset > rl.Window {
+		t.Errorf("reset got %s, retry after %s", res.Reset, res.RetryAfter)
+	}
+
+	// 其它 key 不受影响
+	if res, _ := l.Allow(key+":other", rl); !res.Allowed {
+		t.Error("other key should be allowed")
+	}
+
+	time.Sleep(rl.Window + 50*time.Millisecond)
+
+	if res, _ := l.Allow(key, rl); !res.Allowed {
+		t.Error("request after window should be allowed")
+	}
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	testRateLimiter(t, NewMemoryRateLimiter())
+}
+
+// 短窗口规则触发的清理不能删除长窗口规则未补满的 bucket
+func TestMemoryRateLimiter_Sweep(t *testing.T) {
+	l := NewMemoryRateLimiter().(*memoryRateLimiter)
+
+	hourly := RateLimit{Limit: 2, Window: time.Hour}
+	secondly := RateLimit{Limit: 2, Window: time.Second}
+
+	for i := 0; i < hourly.Limit; i++ {
+		_, _ = l.Allow("hourly", hourly)
+	}
+	_, _ = l.Allow("secondly", secondly)
+
+	now := time.Now()
+
+	l.sweep(now.Add(2 * memoryRateLimiterSweepInterval))
+	if _, ok := l.buckets["hourly"]; !ok {
+		t.Fatal("hourly bucket not refilled should not be swept")
+	}
+	if _, ok := l.buckets["secondly"]; ok {
+		t.Error("refilled secondly bucket should be swept")
+	}
+	if res, _ := l.Allow("hourly", hourly); res.Allowed {
+		t.Error("hourly limit should not be reset by sweep")
+	}
+
+	l.sweep(now.Add(2 * time.Hour))
+	if _, ok := l.buckets["hourly"]; ok {
+		t.Error("refilled hourly bucket should be swept")
+	}
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	if !yago.Config.IsSet("redis.addr") {
+		t.Skip("redis is not configured")
+	}
+	if err := rds.Ins().HealthCheck(context.Background()); err != nil {
+		t.Skip("redis is not available:", err)
+	}
+
+	testRateLimiter(t, NewRedisRateLimiter("redis", "ratelimit:"))
+}
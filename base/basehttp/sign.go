@@ -0,0 +1,190 @@
+package basehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+	"github.com/hulklab/yago/coms/rds"
+	"github.com/hulklab/yago/libs/signlib"
+)
+
+// 签名校验通过后写入调用方的 app key
+// eg. appKey := c.GetString(basehttp.SignedAppKey)
+const SignedAppKey = "__SignedAppKey__"
+
+// 根据 app key 获取密钥, app key 不存在时返回空字符串
+type SignKeyStore interface {
+	GetSecret(appKey string) (string, error)
+}
+
+// app key => secret, app key 区分大小写
+type SignKeys map[string]string
+
+// 配置中的一个 app key, 使用 table 数组配置, viper 会把 map 的 key 转为小写
+type SignKey struct {
+	AppKey string `mapstructure:"app_key"`
+	Secret string `mapstructure:"secret"`
+}
+
+func (k SignKeys) GetSecret(appKey string) (string, error) {
+	return k[appKey], nil
+}
+
+// 防重放, nonce 在 ttl 内第一次出现时返回 true
+type NonceStore interface {
+	CheckNonce(appKey, nonce string, ttl time.Duration) (bool, error)
+}
+
+type redisNonceStore struct {
+	rdsId  string
+	prefix string
+}
+
+func NewRedisNonceStore(rdsId, prefix string) NonceStore {
+	return &redisNonceStore{rdsId: rdsId, prefix: prefix}
+}
+
+func (s *redisNonceStore) CheckNonce(appKey, nonce string, ttl time.Duration) (bool, error) {
+	_, err := redis.String(rds.Ins(s.rdsId).Set(s.prefix+appKey+":"+nonce, 1, "PX", ttl.Milliseconds(), "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type SignConfig struct {
+	Keys SignKeyStore
+	// 为 nil 时不检查 nonce 是否重复
+	Nonces NonceStore
+	// 时间戳允许的偏差, 前后各 MaxSkew, nonce 保留 2*MaxSkew 以覆盖整个窗口
+	MaxSkew time.Duration
+	// 参与签名的 body 最大字节数
+	MaxBodySize int64
+}
+
+// 从配置段加载, 配置段中的 keys 为 {app_key, secret} 的 table 数组
+//
+//	[openapi_sign]
+//	max_skew = "5m"
+//	nonce_redis_instance_id = "redis"
+//
+//	[[openapi_sign.keys]]
+//	app_key = "Demo"
+//	secret = "secret"
+func LoadSignConfig(section string) *SignConfig {
+	keys := make([]SignKey, 0)
+	if err := yago.Config.UnmarshalKey(section+".keys", &keys); err != nil {
+		log.Fatalf("Fatal error: parse %s.keys err: %s", section, err)
+	}
+
+	signKeys := make(SignKeys, len(keys))
+	for _, k := range keys {
+		if k.AppKey == "" || k.Secret == "" {
+			log.Fatalf("Fatal error: %s.keys app_key and secret are required", section)
+		}
+		signKeys[k.AppKey] = k.Secret
+	}
+
+	conf := &SignConfig{
+		Keys:        signKeys,
+		MaxSkew:     5 * time.Minute,
+		MaxBodySize: 10 << 20,
+	}
+
+	if yago.Config.IsSet(section + ".max_skew") {
+		conf.MaxSkew = yago.Config.GetDuration(section + ".max_skew")
+	}
+	if yago.Config.IsSet(section + ".max_body_size") {
+		conf.MaxBodySize = yago.Config.GetInt64(section + ".max_body_size")
+	}
+
+	if rdsId := yago.Config.GetString(section + ".nonce_redis_instance_id"); rdsId != "" {
+		prefix := yago.Config.GetString(section + ".nonce_prefix")
+		if prefix == "" {
+			prefix = "sign:nonce:"
+		}
+		conf.Nonces = NewRedisNonceStore(rdsId, prefix)
+	}
+
+	return conf
+}
+
+// 校验 signlib 生成的签名, 失败时返回 ErrSign
+// eg. yago.NewHttpGroupRouter("/openapi", basehttp.NewSignVerifier(basehttp.LoadSignConfig("openapi_sign")))
+func NewSignVerifier(conf *SignConfig) yago.HttpHandlerFunc {
+	return func(c *yago.Ctx) {
+		appKey := c.GetHeader(signlib.HeaderAppKey)
+		timestamp := c.GetHeader(signlib.HeaderTimestamp)
+		nonce := c.GetHeader(signlib.HeaderNonce)
+		signature := c.GetHeader(signlib.HeaderSignature)
+
+		if appKey == "" || nonce == "" || signature == "" || !signlib.CheckTimestamp(timestamp, conf.MaxSkew) {
+			c.AbortWithE(yago.ErrSign)
+			return
+		}
+
+		secret, err := conf.Keys.GetSecret(appKey)
+		if err != nil {
+			logger.Ins().Category("http.sign").WithContext(c.Request.Context()).Errorf("get secret of %s err: %s", appKey, err)
+			c.AbortWithE(yago.ErrSystem)
+			return
+		}
+		if secret == "" {
+			c.AbortWithE(yago.ErrSign)
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			if c.Request.ContentLength > conf.MaxBodySize {
+				c.AbortWithE(yago.ErrSign)
+				return
+			}
+
+			body, err = ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, conf.MaxBodySize))
+			if err != nil {
+				c.AbortWithE(yago.ErrSign)
+				return
+			}
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		req := &signlib.Request{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Params:    c.Request.URL.Query(),
+			Body:      body,
+			Timestamp: timestamp,
+			Nonce:     nonce,
+		}
+
+		if !signlib.Verify(secret, req, signature) {
+			c.AbortWithE(yago.ErrSign)
+			return
+		}
+
+		// 签名通过后再记录 nonce, 避免伪造的请求占用 nonce
+		// 时间戳最多超前 MaxSkew, 在之后的 MaxSkew 内仍然有效, nonce 需要保留 2*MaxSkew
+		if conf.Nonces != nil {
+			ok, err := conf.Nonces.CheckNonce(appKey, nonce, 2*conf.MaxSkew)
+			if err != nil {
+				logger.Ins().Category("http.sign").WithContext(c.Request.Context()).Errorf("check nonce err: %s", err)
+				c.AbortWithE(yago.ErrSystem)
+				return
+			}
+			if !ok {
+				c.AbortWithE(yago.ErrSign)
+				return
+			}
+		}
+
+		c.Set(SignedAppKey, appKey)
+	}
+}
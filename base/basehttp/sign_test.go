@@ -0,0 +1,119 @@
+package basehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/libs/signlib"
+)
+
+// go test -v ./base/basehttp -test.run "TestLoadSignConfig|TestSignVerifier"
+
+// 按 ttl 过期的内存 nonce
+type memoryNonceStore struct {
+	mu     sync.Mutex
+	expire map[string]time.Time
+}
+
+func (s *memoryNonceStore) CheckNonce(appKey, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := appKey + ":" + nonce
+	if time.Now().Before(s.expire[key]) {
+		return false, nil
+	}
+	s.expire[key] = time.Now().Add(ttl)
+	return true, nil
+}
+
+func TestLoadSignConfig(t *testing.T) {
+	yago.Config.SetConfigType("toml")
+	err := yago.Config.MergeConfig(strings.NewReader(`
+[test_sign]
+max_skew = "1m"
+
+[[test_sign.keys]]
+app_key = "DemoApp"
+secret = "Secret1"
+
+[[test_sign.keys]]
+app_key = "demoapp"
+secret = "secret2"
+`))
+	if err != nil {
+		t.Fatal("merge config err:", err)
+	}
+
+	conf := LoadSignConfig("test_sign")
+
+	// app key 区分大小写
+	cases := map[string]string{"DemoApp": "Secret1", "demoapp": "secret2", "DEMOAPP": ""}
+	for appKey, want := range cases {
+		if secret, _ := conf.Keys.GetSecret(appKey); secret != want {
+			t.Errorf("secret of %s got %q, want %q", appKey, secret, want)
+		}
+	}
+
+	if conf.MaxSkew != time.Minute {
+		t.Errorf("max skew got %s", conf.MaxSkew)
+	}
+	if conf.Nonces != nil {
+		t.Error("nonce store should be nil without nonce_redis_instance_id")
+	}
+}
+
+func TestSignVerifier_Replay(t *testing.T) {
+	conf := &SignConfig{
+		Keys:        SignKeys{"demo": "secret"},
+		Nonces:      &memoryNonceStore{expire: make(map[string]time.Time)},
+		MaxSkew:     2 * time.Second,
+		MaxBodySize: 1 << 10,
+	}
+
+	e := gin.New()
+	e.GET("/openapi/order", ginHandler(NewSignVerifier(conf)), ginHandler(func(c *yago.Ctx) {
+		c.SetData(c.GetString(SignedAppKey))
+	}))
+
+	// 时间戳超前 MaxSkew, 在 nonce 只保留 MaxSkew 时, 过期后时间戳仍然有效
+	r := &signlib.Request{
+		Method:    http.MethodGet,
+		Path:      "/openapi/order",
+		Timestamp: strconv.FormatInt(time.Now().Unix()+2, 10),
+		Nonce:     signlib.Nonce(),
+	}
+	sig := signlib.Sign("secret", r)
+
+	send := func() string {
+		req := httptest.NewRequest(http.MethodGet, r.Path, nil)
+		req.Header.Set(signlib.HeaderAppKey, "demo")
+		req.Header.Set(signlib.HeaderTimestamp, r.Timestamp)
+		req.Header.Set(signlib.HeaderNonce, r.Nonce)
+		req.Header.Set(signlib.HeaderSignature, sig)
+
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	if body := send(); !strings.Contains(body, `"data":"demo"`) {
+		t.Fatalf("first request got %s", body)
+	}
+
+	time.Sleep(conf.MaxSkew + 100*time.Millisecond)
+
+	if !signlib.CheckTimestamp(r.Timestamp, conf.MaxSkew) {
+		t.Fatal("timestamp should still be valid")
+	}
+	if body := send(); !strings.Contains(body, `"errno":`+strconv.Itoa(yago.ErrSign.Code())) {
+		t.Errorf("replayed request got %s", body)
+	}
+}
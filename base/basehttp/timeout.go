@@ -0,0 +1,105 @@
+package basehttp
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hulklab/yago"
+)
+
+// 路由 metadata 实现此接口设置单个路由的超时, 优先于路由组的超时, 返回 0 时不覆盖
+// eg. ghttp.Root.Post("/report/export", h.ExportAction).WithMetadata(ghttp.HttpMetadata{Timeout: time.Minute})
+type RequestTimeouter interface {
+	RequestTimeout() time.Duration
+}
+
+type TimeoutRule struct {
+	// 路由模板, 可以带请求方法, eg. "POST /report/export"
+	Route   string        `mapstructure:"route"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// 为请求设置 deadline, 客户端可以通过 X-Request-Timeout 缩短, 路由 metadata 优先
+// 超时是协作式的, 不会中断 handler, 只有把 c.GetContext() 传给 service, orm, rds 和 third 时才会提前返回,
+// handler 忽略 ctx 时会执行完, 没有写响应时返回 ErrTimeout, 已经写了的响应照常返回
+// eg. yago.NewHttpGroupRouter("/api", basehttp.NewTimeout(3*time.Second))
+func NewTimeout(timeout time.Duration) yago.HttpHandlerFunc {
+	return func(c *yago.Ctx) {
+		t := timeout
+		if r, ok := c.GetHttpRouter(); ok {
+			if v, ok := r.Metadata.(RequestTimeouter); ok && v.RequestTimeout() > 0 {
+				t = v.RequestTimeout()
+			}
+		}
+
+		requestTimeout(c, t)
+	}
+}
+
+// 按配置段中的 routes 设置超时, 未配置的路由使用 metadata 或 default, app.http_timeout_on 开启后作为默认中间件
+// eg. yago.NewHttpGroupRouter("/api", basehttp.NewRouteTimeout("http_timeout"))
+func NewRouteTimeout(section string) yago.HttpHandlerFunc {
+	rules := make([]TimeoutRule, 0)
+	if err := yago.Config.UnmarshalKey(section+".routes", &rules); err != nil {
+		log.Fatalf("Fatal error: parse %s.routes err: %s", section, err)
+	}
+
+	// 路由模板或 "METHOD 路由模板" => timeout
+	routes := make(map[string]time.Duration, len(rules))
+	for _, r := range rules {
+		if r.Timeout <= 0 {
+			log.Fatalf("Fatal error: http_timeout route %s timeout must be positive", r.Route)
+		}
+
+		route := strings.Fields(r.Route)
+		if len(route) == 2 {
+			r.Route = strings.ToUpper(route[0]) + " " + route[1]
+		}
+		routes[r.Route] = r.Timeout
+	}
+
+	defaultTimeout := yago.Config.GetDuration(section + ".default")
+
+	return func(c *yago.Ctx) {
+		timeout, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			timeout, ok = routes[c.FullPath()]
+		}
+
+		if !ok {
+			timeout = defaultTimeout
+			if r, ok := c.GetHttpRouter(); ok {
+				if v, ok := r.Metadata.(RequestTimeouter); ok && v.RequestTimeout() > 0 {
+					timeout = v.RequestTimeout()
+				}
+			}
+		}
+
+		requestTimeout(c, timeout)
+	}
+}
+
+// timeout 为 0 时只使用 X-Request-Timeout, 都没有时不设置 deadline
+// handler 执行完时已经超时且没有响应, 返回 ErrTimeout
+func requestTimeout(c *yago.Ctx, timeout time.Duration) {
+	if v, ok := yago.ParseRequestTimeout(c.GetHeader(yago.RequestTimeoutHeader)); ok && (timeout <= 0 || v < timeout) {
+		timeout = v
+	}
+
+	if timeout <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+		c.AbortWithE(yago.ErrTimeout)
+	}
+}
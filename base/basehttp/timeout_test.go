@@ -0,0 +1,101 @@
+package basehttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+)
+
+// go test -v ./base/basehttp -test.run TestTimeout
+
+func newTimeoutTestEngine(timeout time.Duration, handler yago.HttpHandlerFunc) *gin.Engine {
+	e := gin.New()
+	e.GET("/", ginHandler(NewTimeout(timeout)), ginHandler(handler))
+	return e
+}
+
+func getTimeoutTest(e *gin.Engine, headers map[string]string) (*httptest.ResponseRecorder, time.Duration) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w, time.Since(start)
+}
+
+func isTimeoutTest(w *httptest.ResponseRecorder) bool {
+	return strings.Contains(w.Body.String(), `"errno":13,`)
+}
+
+func TestTimeout_Cooperative(t *testing.T) {
+	timeout := 50 * time.Millisecond
+
+	// 使用 ctx 的 handler 在 deadline 时返回
+	e := newTimeoutTestEngine(timeout, func(c *yago.Ctx) {
+		select {
+		case <-c.GetContext().Done():
+			c.SetError(c.GetContext().Err())
+		case <-time.After(time.Second):
+			c.SetData("done")
+		}
+	})
+	w, cost := getTimeoutTest(e, nil)
+	if !isTimeoutTest(w) || cost > 500*time.Millisecond {
+		t.Errorf("handler using ctx got %s in %s", w.Body.String(), cost)
+	}
+
+	// 忽略 ctx 的 handler 不会被中断, 执行完后没有响应时返回超时
+	e = newTimeoutTestEngine(timeout, func(c *yago.Ctx) {
+		time.Sleep(2 * timeout)
+	})
+	w, cost = getTimeoutTest(e, nil)
+	if !isTimeoutTest(w) || cost < 2*timeout {
+		t.Errorf("handler ignoring ctx got %s in %s", w.Body.String(), cost)
+	}
+
+	// 忽略 ctx 的 handler 写了响应时照常返回
+	e = newTimeoutTestEngine(timeout, func(c *yago.Ctx) {
+		time.Sleep(2 * timeout)
+		c.SetData("late")
+	})
+	w, _ = getTimeoutTest(e, nil)
+	if isTimeoutTest(w) || !strings.Contains(w.Body.String(), "late") {
+		t.Errorf("handler ignoring ctx with response got %s", w.Body.String())
+	}
+}
+
+func TestTimeout_Header(t *testing.T) {
+	var deadline time.Duration
+	e := newTimeoutTestEngine(time.Minute, func(c *yago.Ctx) {
+		d, ok := c.GetContext().Deadline()
+		if !ok {
+			deadline = 0
+			return
+		}
+		deadline = time.Until(d)
+	})
+
+	getTimeoutTest(e, nil)
+	if deadline <= 50*time.Second {
+		t.Errorf("deadline without header got %s", deadline)
+	}
+
+	// 只能缩短
+	getTimeoutTest(e, map[string]string{yago.RequestTimeoutHeader: "100"})
+	if deadline <= 0 || deadline > 100*time.Millisecond {
+		t.Errorf("deadline with shorter header got %s", deadline)
+	}
+
+	getTimeoutTest(e, map[string]string{yago.RequestTimeoutHeader: "2m"})
+	if deadline <= 50*time.Second || deadline > time.Minute {
+		t.Errorf("deadline with longer header got %s", deadline)
+	}
+}
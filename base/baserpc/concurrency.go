@@ -0,0 +1,108 @@
+package baserpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/libs/semalib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	methodPriorities   = make(map[string]semalib.Priority)
+	methodPrioritiesMu sync.RWMutex
+)
+
+// 设置 rpc 方法在并发限制中的优先级, 默认为 PriorityNormal
+// eg. baserpc.SetMethodPriority("/app.demopb.Home/Hello", semalib.PriorityCritical)
+func SetMethodPriority(fullMethod string, p semalib.Priority) {
+	methodPrioritiesMu.Lock()
+	defer methodPrioritiesMu.Unlock()
+
+	methodPriorities[fullMethod] = p
+}
+
+func getMethodPriority(fullMethod string) semalib.Priority {
+	methodPrioritiesMu.RLock()
+	defer methodPrioritiesMu.RUnlock()
+
+	return methodPriorities[fullMethod]
+}
+
+func loadMethodPriorities(section string) {
+	for _, m := range yago.Config.GetStringSlice(section + ".rpc_critical_methods") {
+		SetMethodPriority(m, semalib.PriorityCritical)
+	}
+	for _, m := range yago.Config.GetStringSlice(section + ".rpc_batch_methods") {
+		SetMethodPriority(m, semalib.PriorityBatch)
+	}
+}
+
+// 健康检查不受限制, app.http_concurrency_limit_on 开启时 http gateway 的调用已经经过 http 的并发限制, 也不受限制
+func skipConcurrencyLimit(ctx context.Context, method string, skipGateway bool) bool {
+	if isHealthMethod(method) {
+		return true
+	}
+
+	if !skipGateway {
+		return false
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if _, ok := p.Addr.(gatewayAddr); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 并发超出上限时返回 ErrOverloaded, 转换为 codes.Unavailable
+// 等待中调用方的 ctx 取消或超时返回 codes.Canceled 或 codes.DeadlineExceeded, 不算作过载
+// app.rpc_concurrency_limit_on 开启后使用 yago.ConcurrencyLimiter() 作为默认拦截器
+func NewConcurrencyLimitUnaryInterceptor(l *semalib.Limiter) grpc.UnaryServerInterceptor {
+	skipGateway := yago.Config.GetBool("app.http_concurrency_limit_on")
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if skipConcurrencyLimit(ctx, info.FullMethod, skipGateway) {
+			return handler(ctx, req)
+		}
+
+		release, err := l.Acquire(ctx, getMethodPriority(info.FullMethod))
+		if err != nil {
+			return nil, acquireErr(err)
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+func NewConcurrencyLimitStreamInterceptor(l *semalib.Limiter) grpc.StreamServerInterceptor {
+	skipGateway := yago.Config.GetBool("app.http_concurrency_limit_on")
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skipConcurrencyLimit(ss.Context(), info.FullMethod, skipGateway) {
+			return handler(srv, ss)
+		}
+
+		release, err := l.Acquire(ss.Context(), getMethodPriority(info.FullMethod))
+		if err != nil {
+			return acquireErr(err)
+		}
+		defer release()
+
+		return handler(srv, ss)
+	}
+}
+
+func acquireErr(err error) error {
+	if errors.Is(err, semalib.ErrLimitExceeded) {
+		return yago.ErrOverloaded
+	}
+	return status.FromContextError(err).Err()
+}
@@ -0,0 +1,412 @@
+package baserpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/hulklab/yago"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+type httpRule struct {
+	method string
+	path   string
+	// "*" 表示整个 body 对应请求消息, 为空表示没有 body, 其他表示 body 对应的字段
+	body string
+	// path 中的参数对应的字段, 如 {user.id}, {name=users/*}
+	pathParams []pathParam
+}
+
+// 字段的值由模板中的字面量和 gin 参数按 / 拼接, 如 {name=users/*} 对应 users/:name
+type pathParam struct {
+	field string
+	// 字面量, 或以 : 和 * 开头的 gin 参数
+	segments []string
+}
+
+func (p pathParam) value(c *yago.Ctx) string {
+	values := make([]string, 0, len(p.segments))
+	for _, seg := range p.segments {
+		switch seg[0] {
+		case ':':
+			values = append(values, c.Param(seg[1:]))
+		case '*':
+			// gin 的 catch-all 参数以 / 开头
+			values = append(values, strings.TrimPrefix(c.Param(seg[1:]), "/"))
+		default:
+			values = append(values, seg)
+		}
+	}
+	return strings.Join(values, "/")
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{([\w.]+)(=[^}]*)?\}`)
+
+var gatewayMarshaler = protojson.MarshalOptions{UseProtoNames: true}
+var gatewayUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// 将注册到 yago.RpcRegistry 的 rpc 服务挂载到 http 路由组上, 不传 services 时挂载全部服务, 只支持 unary 方法
+// 有 google.api.http 注解的方法按注解生成路由, 否则使用 POST {group}/{package.Service}/{Method}
+// eg. baserpc.RegisterHttpGateway(yago.NewHttpGroupRouter("/rpc"), "app.demopb.Home")
+func RegisterHttpGateway(g *yago.HttpGroupRouter, services ...string) {
+	// services are registered in package init, wait for all of them
+	yago.AddRouterInitHook(func() error {
+		names := services
+		if len(names) == 0 {
+			for name := range yago.RpcRegistry.GetServiceInfo() {
+				// 注册到 deprecated yago.RpcServer 的服务不能挂载
+				if _, _, ok := yago.RpcRegistry.GetService(name); ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+		}
+
+		for _, name := range names {
+			desc, impl, ok := yago.RpcRegistry.GetService(name)
+			if !ok {
+				return fmt.Errorf("rpc service %s is not registered to yago.RpcRegistry", name)
+			}
+
+			for _, md := range desc.Methods {
+				for _, rule := range getHttpRules(desc.ServiceName, md.MethodName) {
+					addGatewayRouter(g, rule, newGatewayAction(desc, impl, md, rule))
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func addGatewayRouter(g *yago.HttpGroupRouter, rule httpRule, action yago.HttpHandlerFunc) *yago.HttpRouter {
+	switch rule.method {
+	case http.MethodGet:
+		return g.Get(rule.path, action)
+	case http.MethodPost:
+		return g.Post(rule.path, action)
+	case http.MethodPut:
+		return g.Put(rule.path, action)
+	case http.MethodDelete:
+		return g.Delete(rule.path, action)
+	case http.MethodPatch:
+		return g.Patch(rule.path, action)
+	default:
+		return g.Any(rule.path, action)
+	}
+}
+
+func getHttpRules(service, method string) []httpRule {
+	defaultRules := []httpRule{{
+		method: http.MethodPost,
+		path:   "/" + service + "/" + method,
+		body:   "*",
+	}}
+
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return defaultRules
+	}
+
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return defaultRules
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil || md.Options() == nil {
+		return defaultRules
+	}
+
+	rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil || rule.Pattern == nil {
+		return defaultRules
+	}
+
+	rules := []httpRule{toHttpRule(rule)}
+	for _, r := range rule.AdditionalBindings {
+		rules = append(rules, toHttpRule(r))
+	}
+
+	return rules
+}
+
+func toHttpRule(rule *annotations.HttpRule) httpRule {
+	r := httpRule{body: rule.Body}
+
+	switch p := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		r.method, r.path = http.MethodGet, p.Get
+	case *annotations.HttpRule_Post:
+		r.method, r.path = http.MethodPost, p.Post
+	case *annotations.HttpRule_Put:
+		r.method, r.path = http.MethodPut, p.Put
+	case *annotations.HttpRule_Delete:
+		r.method, r.path = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		r.method, r.path = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		r.method, r.path = strings.ToUpper(p.Custom.Kind), p.Custom.Path
+	}
+
+	path, params, err := toGinPath(r.path)
+	if err != nil {
+		log.Fatalf("Fatal error: rpc gateway %s %s: %s", r.method, r.path, err)
+	}
+	r.path, r.pathParams = path, params
+
+	return r
+}
+
+// {user.id} => :user_id, {name=users/*} => users/:name, {name=**} => *name, {name=users/*/books/*} => users/:name_1/books/:name_2
+// gin 不支持的模板返回错误, 如变量不是完整的路径段, ** 不在最后, 以及 :verb 后缀
+func toGinPath(template string) (string, []pathParam, error) {
+	var params []pathParam
+	var b strings.Builder
+
+	rest := template
+	for {
+		loc := pathParamRegexp.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+
+		literal := rest[:loc[0]]
+		if strings.ContainsAny(literal, "{}*:") || !strings.HasSuffix(literal, "/") {
+			return "", nil, fmt.Errorf("unsupported path template")
+		}
+		b.WriteString(literal)
+
+		field, pattern := rest[loc[2]:loc[3]], "*"
+		if loc[4] >= 0 {
+			pattern = rest[loc[4]+1 : loc[5]]
+		}
+
+		p, err := toPathParam(field, pattern)
+		if err != nil {
+			return "", nil, err
+		}
+		params = append(params, p)
+		b.WriteString(strings.Join(p.segments, "/"))
+
+		rest = rest[loc[1]:]
+		if rest != "" && !strings.HasPrefix(rest, "/") {
+			return "", nil, fmt.Errorf("unsupported path template")
+		}
+	}
+
+	if strings.ContainsAny(rest, "{}*:") {
+		return "", nil, fmt.Errorf("unsupported path template")
+	}
+	b.WriteString(rest)
+
+	// gin 的 catch-all 参数只能在最后
+	path := b.String()
+	if i := strings.Index(path, "/*"); i >= 0 && strings.Contains(path[i+1:], "/") {
+		return "", nil, fmt.Errorf("** must be at the end of path")
+	}
+
+	return path, params, nil
+}
+
+func toPathParam(field, pattern string) (pathParam, error) {
+	segs := strings.Split(pattern, "/")
+
+	wildcards := 0
+	for _, seg := range segs {
+		if seg == "*" || seg == "**" {
+			wildcards++
+		}
+	}
+
+	p := pathParam{field: field}
+	name, n := ginParamName(field), 0
+
+	for i, seg := range segs {
+		switch {
+		case seg == "*" || seg == "**" && i == len(segs)-1:
+			n++
+			param := name
+			if wildcards > 1 {
+				param = name + "_" + strconv.Itoa(n)
+			}
+			if seg == "**" {
+				p.segments = append(p.segments, "*"+param)
+			} else {
+				p.segments = append(p.segments, ":"+param)
+			}
+		case seg == "" || strings.ContainsAny(seg, "{}*:"):
+			return p, fmt.Errorf("unsupported path template {%s=%s}", field, pattern)
+		default:
+			p.segments = append(p.segments, seg)
+		}
+	}
+
+	return p, nil
+}
+
+func ginParamName(field string) string {
+	return strings.ReplaceAll(field, ".", "_")
+}
+
+type gatewayAddr string
+
+func (a gatewayAddr) Network() string {
+	return "tcp"
+}
+
+func (a gatewayAddr) String() string {
+	return string(a)
+}
+
+func newGatewayAction(desc *grpc.ServiceDesc, impl interface{}, md grpc.MethodDesc, rule httpRule) yago.HttpHandlerFunc {
+	return func(c *yago.Ctx) {
+		// http header => incoming metadata, then rpc interceptors like auth work the same
+		mds := metadata.MD{}
+		for k, v := range c.Request.Header {
+			mds.Append(k, v...)
+		}
+		ctx := metadata.NewIncomingContext(c.Request.Context(), mds)
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: gatewayAddr(c.Request.RemoteAddr)})
+
+		dec := func(v interface{}) error {
+			if err := decodeGatewayRequest(c, protov1.MessageV2(v), rule); err != nil {
+				return yago.NewErr(yago.ErrParam, err.Error())
+			}
+			return nil
+		}
+
+		reply, err := md.Handler(impl, ctx, dec, yago.RpcRegistry.UnaryInterceptor())
+		if err != nil {
+			c.SetError(StatusToErr(err))
+			return
+		}
+
+		b, err := gatewayMarshaler.Marshal(protov1.MessageV2(reply))
+		if err != nil {
+			c.SetError(yago.NewErr(yago.ErrSystem, err.Error()))
+			return
+		}
+
+		c.SetData(json.RawMessage(b))
+	}
+}
+
+func decodeGatewayRequest(c *yago.Ctx, m proto.Message, rule httpRule) error {
+	data := make(map[string]interface{})
+
+	if rule.body != "" {
+		body, err := c.GetRawData()
+		if err != nil {
+			return err
+		}
+
+		if len(bytes.TrimSpace(body)) > 0 {
+			var v interface{}
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			// keep int64 precision
+			decoder.UseNumber()
+			if err := decoder.Decode(&v); err != nil {
+				return err
+			}
+
+			if rule.body == "*" {
+				obj, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("request body must be json object")
+				}
+				data = obj
+			} else {
+				data[rule.body] = v
+			}
+		}
+	}
+
+	desc := m.ProtoReflect().Descriptor()
+
+	for _, p := range rule.pathParams {
+		setGatewayField(data, desc, p.field, []string{p.value(c)})
+	}
+
+	// 未被 body 覆盖的字段可以通过 query 传递
+	if rule.body != "*" {
+		for k, v := range c.Request.URL.Query() {
+			if _, ok := data[k]; !ok {
+				setGatewayField(data, desc, k, v)
+			}
+		}
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return gatewayUnmarshaler.Unmarshal(b, m)
+}
+
+// set path or query param into json data, value is converted by field kind
+func setGatewayField(data map[string]interface{}, desc protoreflect.MessageDescriptor, path string, values []string) {
+	names := strings.Split(path, ".")
+
+	for i, name := range names {
+		fd := desc.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = desc.Fields().ByJSONName(name)
+		}
+		if fd == nil {
+			return
+		}
+
+		key := string(fd.Name())
+
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return
+			}
+
+			sub, ok := data[key].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				data[key] = sub
+			}
+			data, desc = sub, fd.Message()
+			continue
+		}
+
+		if fd.IsList() {
+			list := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				list = append(list, gatewayFieldValue(fd, v))
+			}
+			data[key] = list
+		} else if len(values) > 0 {
+			data[key] = gatewayFieldValue(fd, values[0])
+		}
+	}
+}
+
+// protojson accepts string for numbers and enums, but not for bool
+func gatewayFieldValue(fd protoreflect.FieldDescriptor, v string) interface{} {
+	if fd.Kind() == protoreflect.BoolKind {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
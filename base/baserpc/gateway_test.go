@@ -0,0 +1,95 @@
+package baserpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"google.golang.org/grpc/peer"
+)
+
+// go test -v ./base/baserpc -test.run "TestToGinPath|TestSkipConcurrencyLimit"
+
+func TestToGinPath(t *testing.T) {
+	cases := []struct {
+		template string
+		path     string
+		target   string
+		values   map[string]string
+	}{
+		{"/v1/users/{id}", "/v1/users/:id", "/v1/users/12", map[string]string{"id": "12"}},
+		{"/v1/users/{user.id}/orders", "/v1/users/:user_id/orders", "/v1/users/12/orders", map[string]string{"user.id": "12"}},
+		{"/v1/{name=users/*}", "/v1/users/:name", "/v1/users/12", map[string]string{"name": "users/12"}},
+		{"/v1/{name=users/*/books/*}", "/v1/users/:name_1/books/:name_2", "/v1/users/12/books/3", map[string]string{"name": "users/12/books/3"}},
+		{"/v1/{parent=shelves/*}/books/{id}", "/v1/shelves/:parent/books/:id", "/v1/shelves/1/books/2", map[string]string{"parent": "shelves/1", "id": "2"}},
+		{"/v1/files/{path=**}", "/v1/files/*path", "/v1/files/a/b.txt", map[string]string{"path": "a/b.txt"}},
+		{"/v1/{name=files/**}", "/v1/files/*name", "/v1/files/a/b.txt", map[string]string{"name": "files/a/b.txt"}},
+	}
+
+	for _, c := range cases {
+		path, params, err := toGinPath(c.template)
+		if err != nil || path != c.path {
+			t.Errorf("%s got %s %v, want %s", c.template, path, err, c.path)
+			continue
+		}
+
+		// 注册到 gin 后能匹配, 并还原出字段的值
+		values := make(map[string]string)
+		e := gin.New()
+		e.GET(path, func(ctx *gin.Context) {
+			for _, p := range params {
+				values[p.field] = p.value(&yago.Ctx{Context: ctx})
+			}
+		})
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.target, nil))
+
+		if w.Code != http.StatusOK || !reflect.DeepEqual(values, c.values) {
+			t.Errorf("%s match %s got %d %v, want %v", c.template, c.target, w.Code, values, c.values)
+		}
+	}
+}
+
+func TestToGinPath_Unsupported(t *testing.T) {
+	templates := []string{
+		"/v1/{name=**}/books",
+		"/v1/{name=users/**/books}",
+		"/v1/users/{id}:cancel",
+		"/v1/user{id}",
+		"/v1/{name=users/x*}",
+		"/v1/{name=users//*}",
+	}
+
+	for _, template := range templates {
+		if path, _, err := toGinPath(template); err == nil {
+			t.Errorf("%s should be unsupported, got %s", template, path)
+		}
+	}
+}
+
+func TestSkipConcurrencyLimit(t *testing.T) {
+	gateway := peer.NewContext(context.Background(), &peer.Peer{Addr: gatewayAddr("127.0.0.1:1234")})
+
+	cases := []struct {
+		name        string
+		ctx         context.Context
+		method      string
+		skipGateway bool
+		skip        bool
+	}{
+		{"health", context.Background(), "/grpc.health.v1.Health/Check", false, true},
+		{"rpc", context.Background(), "/app.demopb.Home/Hello", true, false},
+		{"gateway with http limiter", gateway, "/app.demopb.Home/Hello", true, true},
+		{"gateway without http limiter", gateway, "/app.demopb.Home/Hello", false, false},
+	}
+
+	for _, c := range cases {
+		if got := skipConcurrencyLimit(c.ctx, c.method, c.skipGateway); got != c.skip {
+			t.Errorf("%s got %v, want %v", c.name, got, c.skip)
+		}
+	}
+}
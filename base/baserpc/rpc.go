@@ -0,0 +1,177 @@
+package baserpc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type BaseRpc struct{}
+
+func init() {
+	// http gateway also calls rpc services through interceptors, so they are registered even if rpc is disabled
+	yago.AddAppInitHook(func(app *yago.App) error {
+		initErrCodes()
+
+		// 顺序：错误转换 -> 访问日志 -> 并发限制 -> panic 恢复，日志中可以记录到被包裹的系统错误
+		interceptors := yago.RpcDefaultInterceptors()
+
+		if !yago.Config.GetBool("app.rpc_disable_default_err_status") {
+			interceptors.UseUnary(errStatusUnaryInterceptor)
+			interceptors.UseStream(errStatusStreamInterceptor)
+		}
+
+		if !yago.Config.GetBool("app.rpc_disable_default_log") {
+			interceptors.UseUnary(logUnaryInterceptor)
+			interceptors.UseStream(logStreamInterceptor)
+		}
+
+		if yago.Config.GetBool("app.rpc_concurrency_limit_on") {
+			loadMethodPriorities("concurrency")
+			limiter := yago.ConcurrencyLimiter()
+			interceptors.UseUnary(NewConcurrencyLimitUnaryInterceptor(limiter))
+			interceptors.UseStream(NewConcurrencyLimitStreamInterceptor(limiter))
+		}
+
+		if !yago.Config.GetBool("app.rpc_disable_default_recovery") {
+			interceptors.UseUnary(recoveryUnaryInterceptor)
+			interceptors.UseStream(recoveryStreamInterceptor)
+		}
+
+		return nil
+	})
+}
+
+func errStatusUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, ErrToStatus(err).Err()
+	}
+
+	return resp, nil
+}
+
+func errStatusStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	if err != nil {
+		return ErrToStatus(err).Err()
+	}
+
+	return nil
+}
+
+func newLogInfo(ctx context.Context, method string) logrus.Fields {
+	logInfo := logrus.Fields{
+		"method":   method,
+		"consume":  0,
+		"category": "rpc.server",
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		logInfo["peer"] = p.Addr.String()
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		logInfo["metadata"] = md
+	}
+
+	return logInfo
+}
+
+// health check is called frequently by load balancer, no need to log
+func isHealthMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+func logUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if isHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	logInfo := newLogInfo(ctx, info.FullMethod)
+	logInfo["params"] = req
+
+	begin := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	logInfo["consume"] = time.Since(begin).Nanoseconds() / 1e6
+
+	if err != nil {
+		logInfo["hint"] = err.Error()
+		logInfo["code"] = ErrToStatus(err).Code().String()
+		logger.Ins().WithContext(ctx).WithFields(logInfo).Error()
+	} else {
+		logInfo["result"] = resp
+		logger.Ins().WithContext(ctx).WithFields(logInfo).Info()
+	}
+
+	return resp, err
+}
+
+func logStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isHealthMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	logInfo := newLogInfo(ss.Context(), info.FullMethod)
+
+	begin := time.Now()
+
+	err := handler(srv, ss)
+
+	// stream 的耗时为整个 stream 的持续时间
+	logInfo["consume"] = time.Since(begin).Nanoseconds() / 1e6
+
+	if err != nil {
+		logInfo["hint"] = err.Error()
+		logInfo["code"] = ErrToStatus(err).Code().String()
+		logger.Ins().WithContext(ss.Context()).WithFields(logInfo).Error()
+	} else {
+		logger.Ins().WithContext(ss.Context()).WithFields(logInfo).Info()
+	}
+
+	return err
+}
+
+func logPanic(ctx context.Context, method string, p interface{}) {
+	logInfo := newLogInfo(ctx, method)
+	delete(logInfo, "consume")
+	logInfo["hint"] = fmt.Sprintf("panic: %v", p)
+	logInfo["stack"] = string(debug.Stack())
+
+	logger.Ins().WithContext(ctx).WithFields(logInfo).Error()
+}
+
+func recoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(ctx, info.FullMethod, p)
+			err = yago.ErrSystem
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func recoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(ss.Context(), info.FullMethod, p)
+			err = yago.ErrSystem
+		}
+	}()
+
+	return handler(srv, ss)
+}
@@ -0,0 +1,69 @@
+package baserpc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hulklab/yago"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// go test -v ./base/baserpc -test.run TestDefaultUnaryInterceptors
+
+func TestDefaultUnaryInterceptors(t *testing.T) {
+	// panic 日志只需要能创建 logger, 最低等级为 Panic 时不写文件
+	if !yago.Config.IsSet("logger") {
+		yago.Config.Set("logger", map[string]interface{}{
+			"formatter": "json", "file_path": filepath.Join(t.TempDir(), "app.log"), "level": int64(0),
+			"max_size": int64(1), "max_backups": int64(1), "max_age": int64(1), "compress": false,
+		})
+	}
+
+	call := func(handler grpc.UnaryHandler) *status.Status {
+		info := &grpc.UnaryServerInfo{FullMethod: "/test.Default/Hello"}
+		_, err := errStatusUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return recoveryUnaryInterceptor(ctx, req, info, handler)
+		})
+		return status.Convert(err)
+	}
+
+	s := call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if s.Code() != codes.OK {
+		t.Errorf("ok got %s", s.Code())
+	}
+
+	s = call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, yago.ErrParam
+	})
+	if s.Code() != codes.InvalidArgument {
+		t.Errorf("yago err got %s", s.Code())
+	}
+
+	s = call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("db down")
+	})
+	if s.Code() != codes.Unknown || s.Message() != "db down" {
+		t.Errorf("plain err got %s %s", s.Code(), s.Message())
+	}
+
+	s = call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	if s.Code() != codes.NotFound || s.Message() != "not found" {
+		t.Errorf("status err got %s %s", s.Code(), s.Message())
+	}
+
+	// panic 转换为 ErrSystem, 不暴露 panic 的内容
+	s = call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if s.Code() != codes.Internal || s.Message() != yago.ErrSystem.Error() {
+		t.Errorf("panic got %s %s", s.Code(), s.Message())
+	}
+}
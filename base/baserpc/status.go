@@ -0,0 +1,130 @@
+package baserpc
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hulklab/yago"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const errDomain = "yago"
+
+// yago err code => grpc status code
+var errCodes = make(map[int]codes.Code)
+
+// Usage: baserpc.SetErrCode(g.ErrOrderNotFound, codes.NotFound)
+func SetErrCode(ye yago.Err, code codes.Code) {
+	errCodes[ye.Code()] = code
+}
+
+// 登记错误码时指定的 grpc code, 被 SetErrCode 覆盖, 在 rpc init hook 中错误码登记完成后生成
+func initErrCodes() {
+	for _, ec := range yago.GetErrCodes() {
+		if ec.GrpcCode == codes.OK {
+			continue
+		}
+		if _, ok := errCodes[ec.Code]; !ok {
+			errCodes[ec.Code] = ec.GrpcCode
+		}
+	}
+}
+
+func getErrCode(ye yago.Err) codes.Code {
+	if code, ok := errCodes[ye.Code()]; ok {
+		return code
+	}
+	return codes.Unknown
+}
+
+// 将 yago.Err 或包裹了 yago.Err 的 error 转成 grpc status, errno, errmsg 和 details 放在 status details 中
+// 被包裹的系统错误不会返回给调用方
+func ErrToStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	var ye yago.Err
+	if !errors.As(err, &ye) {
+		return status.New(codes.Unknown, err.Error())
+	}
+
+	metadata := map[string]string{
+		"errno":  strconv.Itoa(ye.Code()),
+		"errmsg": ye.Error(),
+	}
+
+	if details := yago.GetErrDetails(err); len(details) > 0 {
+		if b, e := json.Marshal(details); e == nil {
+			metadata["details"] = string(b)
+		}
+	}
+
+	st := status.New(getErrCode(ye), ye.Error())
+	ds, e := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   strconv.Itoa(ye.Code()),
+		Domain:   errDomain,
+		Metadata: metadata,
+	})
+	if e != nil {
+		return st
+	}
+
+	return ds
+}
+
+type statusErr struct {
+	yago.Err
+	st      *status.Status
+	details map[string]interface{}
+}
+
+func (e *statusErr) Unwrap() error {
+	return e.Err
+}
+
+func (e *statusErr) GRPCStatus() *status.Status {
+	return e.st
+}
+
+// yago.GetErrDetails 可以取出调用方返回的详情
+func (e *statusErr) Details() map[string]interface{} {
+	return e.details
+}
+
+// 将 grpc status 中的 errno 和 errmsg 还原成 yago.Err, 可以通过 errors.As 取出, 详情通过 yago.GetErrDetails 取出,
+// 返回的 error 仍然可以用 status.FromError 取出原始 status
+func StatusToErr(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errDomain {
+			continue
+		}
+
+		errno, e := strconv.Atoi(info.Metadata["errno"])
+		if e != nil {
+			continue
+		}
+
+		se := &statusErr{Err: yago.NewErr(errno, info.Metadata["errmsg"]), st: st}
+		if v, ok := info.Metadata["details"]; ok {
+			_ = json.Unmarshal([]byte(v), &se.details)
+		}
+
+		return se
+	}
+
+	return err
+}
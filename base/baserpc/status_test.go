@@ -0,0 +1,78 @@
+package baserpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// go test -v ./base/baserpc -test.run TestErrToStatus
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	yago.LoadErrCodes()
+	initErrCodes()
+}
+
+func TestErrToStatus(t *testing.T) {
+	errNotFound := yago.RegisterErr("test", 92001, "not found", yago.WithErrGrpcCode(codes.NotFound))
+	errCustom := yago.Err("92002=custom")
+	SetErrCode(errCustom, codes.AlreadyExists)
+	initErrCodes()
+
+	cases := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"builtin", yago.ErrParam, codes.InvalidArgument},
+		{"builtin with message", yago.NewErr(yago.ErrSystem, "db down"), codes.Internal},
+		{"registered", errNotFound, codes.NotFound},
+		{"set by SetErrCode", errCustom, codes.AlreadyExists},
+		{"unregistered", yago.Err("92003=unknown"), codes.Unknown},
+		{"wrapped", yago.WrapErr(yago.ErrForbidden, errors.New("no role")), codes.PermissionDenied},
+		{"status", status.Error(codes.Canceled, "canceled"), codes.Canceled},
+		{"plain error", errors.New("plain"), codes.Unknown},
+	}
+
+	for _, c := range cases {
+		if got := ErrToStatus(c.err).Code(); got != c.code {
+			t.Errorf("%s got code %s, want %s", c.name, got, c.code)
+		}
+	}
+
+	if ErrToStatus(nil).Code() != codes.OK {
+		t.Error("nil err should be ok")
+	}
+}
+
+func TestStatusToErr(t *testing.T) {
+	err := yago.ErrForbidden.WithDetails(map[string]interface{}{"role": "admin"}).WithCause(errors.New("internal reason"))
+	st := ErrToStatus(err)
+
+	if st.Message() != yago.ErrForbidden.Error() {
+		t.Errorf("wrapped system error should not be returned, got %s", st.Message())
+	}
+
+	back := StatusToErr(st.Err())
+
+	var ye yago.Err
+	if !errors.As(back, &ye) || ye.Code() != yago.ErrForbidden.Code() || ye.Error() != yago.ErrForbidden.Error() {
+		t.Errorf("err from status got %v", back)
+	}
+	if details := yago.GetErrDetails(back); details["role"] != "admin" {
+		t.Errorf("details from status got %v", details)
+	}
+	if s, ok := status.FromError(back); !ok || s.Code() != codes.PermissionDenied {
+		t.Error("original status should be kept")
+	}
+
+	plain := status.Error(codes.Canceled, "canceled")
+	if StatusToErr(plain) != plain {
+		t.Error("status without errno should be returned as is")
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/hulklab/yago"
 	"github.com/hulklab/yago/coms/logger"
+	"github.com/hulklab/yago/libs/tracelib"
 	"github.com/levigross/grequests"
 	"github.com/sirupsen/logrus"
 )
@@ -114,6 +115,11 @@ func (a *HttpThird) InitConfig(configSection string) error {
 	a.CertFile = yago.Config.GetString(configSection + ".cert_file")
 	a.maxLoggedRespMsgSizeKB = yago.Config.GetInt64(configSection + ".max_logged_resp_size_kb")
 
+	signAppKey := yago.Config.GetString(configSection + ".sign_app_key")
+	if len(signAppKey) > 0 {
+		a.AddInterceptor(NewSignInterceptor(signAppKey, yago.Config.GetString(configSection+".sign_secret")))
+	}
+
 	proxy := yago.Config.GetString(configSection + ".proxy")
 	if len(proxy) > 0 {
 		proxyURL,err := url.Parse(proxy)
@@ -431,6 +437,9 @@ func (a *HttpThird) getInterceptors() []HttpInterceptor {
 		if !a.disableDefaultInterceptor {
 			a.AddInterceptor(a.logInterceptor)
 		}
+
+		// 传递 trace context 和 deadline(放到最前)
+		a.interceptors = append([]HttpInterceptor{a.traceInterceptor, deadlineInterceptor}, a.interceptors...)
 	})
 
 	return a.interceptors
@@ -467,8 +476,85 @@ func (a *HttpThird) DisableDefaultInterceptor() {
 	a.disableDefaultInterceptor = true
 }
 
+// 作为 tracelib.Carrier 写入请求 header
+type headerCarrier map[string]string
+
+func (c headerCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// ro.Context 中有 trace 时, 创建 client span 并通过 traceparent header 传递给下游
+func (a *HttpThird) traceInterceptor(method, uri string, ro *grequests.RequestOptions, call Caller) (*Response, error) {
+	if ro.Context == nil || !tracelib.SpanContextFromContext(ro.Context).IsValid() {
+		return call(method, uri, ro)
+	}
+
+	ctx, span := tracelib.StartSpan(ro.Context, "HTTP "+method, tracelib.SpanKindClient)
+	defer span.End()
+
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.url", uri)
+
+	// ro.Headers may be shared by all requests, copy before inject
+	headers := make(map[string]string, len(ro.Headers)+2)
+	for k, v := range ro.Headers {
+		headers[k] = v
+	}
+	tracelib.Inject(ctx, headerCarrier(headers))
+
+	ro.Headers = headers
+	ro.Context = ctx
+
+	resp, err := call(method, uri, ro)
+	if err != nil {
+		span.SetError(err)
+		return resp, err
+	}
+
+	if resp != nil && resp.Response != nil && resp.RawResponse != nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			span.SetStatus(tracelib.StatusCodeError, strconv.Itoa(resp.StatusCode))
+		}
+	}
+
+	return resp, err
+}
+
+// ro.Context 有 deadline 时, 通过 X-Request-Timeout 把剩余时间传给下游, 已经超时的不再请求
+func deadlineInterceptor(method, uri string, ro *grequests.RequestOptions, call Caller) (*Response, error) {
+	if ro.Context == nil {
+		return call(method, uri, ro)
+	}
+
+	deadline, ok := ro.Context.Deadline()
+	if !ok {
+		return call(method, uri, ro)
+	}
+
+	if err := ro.Context.Err(); err != nil {
+		return ErrResponse(err), err
+	}
+
+	headers := make(map[string]string, len(ro.Headers)+1)
+	for k, v := range ro.Headers {
+		headers[k] = v
+	}
+	headers[yago.RequestTimeoutHeader] = yago.FormatRequestTimeout(time.Until(deadline))
+	ro.Headers = headers
+
+	return call(method, uri, ro)
+}
+
 func (a *HttpThird) logInterceptor(method, uri string, ro *grequests.RequestOptions, call Caller) (*Response, error) {
 	log := logger.Ins().Category("third.http")
+	if ro.Context != nil {
+		log = log.WithContext(ro.Context)
+	}
 
 	var dataParams map[string]string
 	logParams := make(map[string]string)
@@ -561,6 +647,8 @@ func (a *HttpThird) logInterceptor(method, uri string, ro *grequests.RequestOpti
 	return resp, nil
 }
 
+// opts 中的 Context 用于传递 trace 和 deadline
+// eg. a.Post("/user/create", params, &grequests.RequestOptions{Context: ctx})
 func (a *HttpThird) Post(api string, params map[string]interface{}, opts ...*grequests.RequestOptions) (*Response, error) {
 	return a.call(http.MethodPost, api, params, opts...)
 }
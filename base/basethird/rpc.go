@@ -3,17 +3,21 @@ package basethird
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"sync"
 	"time"
 
 	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/base/baserpc"
 	"github.com/hulklab/yago/coms/logger"
+	"github.com/hulklab/yago/libs/tracelib"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type UnaryClientInterceptor func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error
@@ -35,6 +39,7 @@ type RpcThird struct {
 	streamClientInterceptors        []grpc.StreamClientInterceptor
 	disableDefaultUnaryInterceptor  bool
 	disableDefaultStreamInterceptor bool
+	disableErrDecode                bool
 }
 
 func (a *RpcThird) InitConfig(configSection string) error {
@@ -79,6 +84,16 @@ func (a *RpcThird) GetConn() (*grpc.ClientConn, error) {
 			a.AddStreamClientInterceptor(a.streamClientInterceptor)
 		}
 
+		// 传递 trace context, 在日志插件之前
+		a.unaryClientInterceptors = append([]grpc.UnaryClientInterceptor{traceUnaryClientInterceptor}, a.unaryClientInterceptors...)
+		a.streamClientInterceptors = append([]grpc.StreamClientInterceptor{traceStreamClientInterceptor}, a.streamClientInterceptors...)
+
+		// 将服务端返回的 errno 和 errmsg 还原成 yago.Err(放到最前)
+		if !a.disableErrDecode {
+			a.unaryClientInterceptors = append([]grpc.UnaryClientInterceptor{errDecodeUnaryClientInterceptor}, a.unaryClientInterceptors...)
+			a.streamClientInterceptors = append([]grpc.StreamClientInterceptor{errDecodeStreamClientInterceptor}, a.streamClientInterceptors...)
+		}
+
 		if len(a.unaryClientInterceptors) > 0 {
 			dialOptions = append(dialOptions, grpc.WithUnaryInterceptor(grpcMiddleware.ChainUnaryClient(a.unaryClientInterceptors...)))
 		}
@@ -123,12 +138,19 @@ func (a *RpcThird) AddStreamClientInterceptor(sci grpc.StreamClientInterceptor)
 	a.streamClientInterceptors = append(a.streamClientInterceptors, sci)
 }
 
-func (a *RpcThird) GetCtx() (context.Context, context.CancelFunc) {
+// 返回带有超时的 ctx, 传入调用方的 ctx 时继承其中的 trace 和 deadline, 取两者中更早的 deadline
+// eg. ctx, cancel := a.GetCtx(c.GetContext())
+func (a *RpcThird) GetCtx(parent ...context.Context) (context.Context, context.CancelFunc) {
 	if a.Timeout == 0 {
 		a.Timeout = 12
 	}
 
-	return context.WithTimeout(context.Background(), time.Duration(a.Timeout)*time.Second)
+	ctx := context.Background()
+	if len(parent) > 0 && parent[0] != nil {
+		ctx = parent[0]
+	}
+
+	return context.WithTimeout(ctx, time.Duration(a.Timeout)*time.Second)
 }
 
 // 设置是否要关闭 info 日志
@@ -148,6 +170,109 @@ func (a *RpcThird) DisableDefaultStreamInterceptor() {
 	a.disableDefaultStreamInterceptor = true
 }
 
+func (a *RpcThird) DisableErrDecode() {
+	a.disableErrDecode = true
+}
+
+func errDecodeUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return baserpc.StatusToErr(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+type errDecodeClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errDecodeClientStream) SendMsg(m interface{}) error {
+	return baserpc.StatusToErr(s.ClientStream.SendMsg(m))
+}
+
+func (s *errDecodeClientStream) RecvMsg(m interface{}) error {
+	return baserpc.StatusToErr(s.ClientStream.RecvMsg(m))
+}
+
+func errDecodeStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	clientStream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, baserpc.StatusToErr(err)
+	}
+
+	return &errDecodeClientStream{clientStream}, nil
+}
+
+// ctx 中有 trace 时创建 client span, 并通过 metadata 传递给下游
+func startClientSpan(ctx context.Context, method string) (context.Context, *tracelib.Span) {
+	if !tracelib.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+
+	ctx, span := tracelib.StartSpan(ctx, method, tracelib.SpanKindClient)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tracelib.Inject(ctx, yago.RpcMetadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func endClientSpan(span *tracelib.Span, err error) {
+	if span == nil {
+		return
+	}
+
+	span.SetAttribute("rpc.grpc.status_code", int(status.Code(err)))
+	span.SetError(err)
+	span.End()
+}
+
+func traceUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, span := startClientSpan(ctx, method)
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	endClientSpan(span, err)
+
+	return err
+}
+
+// span 在 stream 结束时结束, 即 RecvMsg 返回错误(包括 io.EOF)时
+type traceClientStream struct {
+	grpc.ClientStream
+	span *tracelib.Span
+}
+
+func (s *traceClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		endClientSpan(s.span, nil)
+	} else if err != nil {
+		endClientSpan(s.span, err)
+	}
+
+	return err
+}
+
+func traceStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, span := startClientSpan(ctx, method)
+
+	clientStream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		endClientSpan(span, err)
+		return nil, err
+	}
+
+	if span == nil {
+		return clientStream, nil
+	}
+
+	return &traceClientStream{ClientStream: clientStream, span: span}, nil
+}
+
 func (a *RpcThird) unaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 	logInfo := logrus.Fields{
 		"address":  a.Address,
@@ -173,14 +298,14 @@ func (a *RpcThird) unaryClientInterceptor(ctx context.Context, method string, re
 
 	if err != nil {
 		logInfo["hint"] = err.Error()
-		logger.Ins().WithFields(logInfo).Error()
+		logger.Ins().WithContext(ctx).WithFields(logInfo).Error()
 	} else {
 		// 默认是日志没关
 		if !a.logInfoOff {
 			logInfo["result"] = reply
 		}
 
-		logger.Ins().WithFields(logInfo).Info()
+		logger.Ins().WithContext(ctx).WithFields(logInfo).Info()
 	}
 	if err != nil {
 		return err
@@ -212,9 +337,9 @@ func (a *RpcThird) streamClientInterceptor(ctx context.Context, desc *grpc.Strea
 
 	if err != nil {
 		logInfo["hint"] = err.Error()
-		logger.Ins().WithFields(logInfo).Error()
+		logger.Ins().WithContext(ctx).WithFields(logInfo).Error()
 	} else {
-		logger.Ins().WithFields(logInfo).Info()
+		logger.Ins().WithContext(ctx).WithFields(logInfo).Info()
 	}
 
 	// 此时只是打开了 stream 通道，还未开始传输数据，只有 stream 得到一个 EOF 的错误时才算传输完成
@@ -0,0 +1,82 @@
+package basethird
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hulklab/yago/libs/signlib"
+	"github.com/levigross/grequests"
+)
+
+// 使用 signlib 对请求签名, 与 basehttp.NewSignVerifier 对应
+// 配置了 sign_app_key 和 sign_secret 时 InitConfig 会自动添加
+// eg. a.AddInterceptor(basethird.NewSignInterceptor("demo", "secret"))
+func NewSignInterceptor(appKey, secret string) HttpInterceptor {
+	return func(method, uri string, ro *grequests.RequestOptions, call Caller) (*Response, error) {
+		before := ro.BeforeRequest
+
+		ro.BeforeRequest = func(req *http.Request) error {
+			if before != nil {
+				if err := before(req); err != nil {
+					return err
+				}
+			}
+
+			return signRequest(req, appKey, secret)
+		}
+
+		return call(method, uri, ro)
+	}
+}
+
+// multipart 请求的 body 不参与签名
+func signRequest(req *http.Request, appKey, secret string) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody && !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		b, err := readRequestBody(req)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	sr := &signlib.Request{
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Params:    req.URL.Query(),
+		Body:      body,
+		Timestamp: signlib.Timestamp(),
+		Nonce:     signlib.Nonce(),
+	}
+
+	req.Header.Set(signlib.HeaderAppKey, appKey)
+	req.Header.Set(signlib.HeaderTimestamp, sr.Timestamp)
+	req.Header.Set(signlib.HeaderNonce, sr.Nonce)
+	req.Header.Set(signlib.HeaderSignature, signlib.Sign(secret, sr))
+
+	return nil
+}
+
+// 读取 body 后保证请求仍可以发送
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return ioutil.ReadAll(rc)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hulklab/yago"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrSessionNotFound = errors.New("auth: session is not found")
+	ErrStoreRequired   = errors.New("auth: store is not configured")
+)
+
+// 认证通过的主体, Subject 一般为用户 id
+type Principal struct {
+	Subject string                 `json:"sub"`
+	Roles   []string               `json:"roles,omitempty"`
+	Ext     map[string]interface{} `json:"ext,omitempty"`
+}
+
+// 签发给客户端的令牌
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type Auth struct {
+	name  string
+	jwt   *JWT
+	store Store
+
+	Issuer        string
+	AccessExpire  time.Duration
+	RefreshExpire time.Duration
+	// refresh token 只能使用一次, 重复使用返回 ErrTokenRevoked
+	RefreshRotation bool
+
+	// 会话有效期, 每次访问后重新计算
+	SessionExpire time.Duration
+	// store 中 key 的前缀
+	Prefix string
+
+	Cookie CookieConfig
+}
+
+type CookieConfig struct {
+	Name     string
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+}
+
+func Ins(id ...string) *Auth {
+	var name string
+
+	if len(id) == 0 {
+		name = "auth"
+	} else if len(id) > 0 {
+		name = id[0]
+	}
+
+	v := yago.Component.Ins(name, func() interface{} {
+		conf := yago.Config.Sub(name)
+		if conf == nil {
+			log.Fatalf("Fatal error: auth config %s is not found", name)
+		}
+
+		// 只使用会话时可以不配置 jwt
+		var j *JWT
+		if conf.IsSet("jwt_secret") || conf.IsSet("jwt_private_key_file") || conf.IsSet("jwt_public_key_file") {
+			var err error
+			j, err = newJWTFromConfig(conf)
+			if err != nil {
+				log.Fatalf("Fatal error: auth %s jwt config err: %s", name, err)
+			}
+		}
+
+		var store Store
+		if rdsId := conf.GetString("redis_instance_id"); rdsId != "" {
+			store = NewRedisStore(rdsId)
+		}
+
+		val := New(j, store)
+		val.name = name
+
+		val.Issuer = conf.GetString("issuer")
+		if val.Issuer == "" {
+			val.Issuer = yago.Config.GetString("app.app_name")
+		}
+		if conf.IsSet("access_expire") {
+			val.AccessExpire = conf.GetDuration("access_expire")
+		}
+		if conf.IsSet("refresh_expire") {
+			val.RefreshExpire = conf.GetDuration("refresh_expire")
+		}
+		if conf.IsSet("refresh_rotation") {
+			val.RefreshRotation = conf.GetBool("refresh_rotation")
+		}
+		if conf.IsSet("session_expire") {
+			val.SessionExpire = conf.GetDuration("session_expire")
+		}
+		if conf.IsSet("prefix") {
+			val.Prefix = conf.GetString("prefix")
+		}
+		if conf.IsSet("cookie_name") {
+			val.Cookie.Name = conf.GetString("cookie_name")
+		}
+		if conf.IsSet("cookie_path") {
+			val.Cookie.Path = conf.GetString("cookie_path")
+		}
+		val.Cookie.Domain = conf.GetString("cookie_domain")
+		val.Cookie.Secure = conf.GetBool("cookie_secure")
+		if conf.IsSet("cookie_http_only") {
+			val.Cookie.HttpOnly = conf.GetBool("cookie_http_only")
+		}
+
+		if val.RefreshRotation && store == nil {
+			log.Fatalf("Fatal error: auth %s refresh_rotation requires redis_instance_id", name)
+		}
+
+		return val
+	})
+
+	return v.(*Auth)
+}
+
+// jwt 为 nil 时不能签发令牌, store 为 nil 时不能使用会话
+func New(j *JWT, store Store) *Auth {
+	return &Auth{
+		name:            "auth",
+		jwt:             j,
+		store:           store,
+		AccessExpire:    2 * time.Hour,
+		RefreshExpire:   7 * 24 * time.Hour,
+		RefreshRotation: store != nil,
+		SessionExpire:   30 * time.Minute,
+		Prefix:          "auth:",
+		Cookie: CookieConfig{
+			Name:     "yago_session",
+			Path:     "/",
+			HttpOnly: true,
+		},
+	}
+}
+
+// jwt_kid 为签发使用的密钥, jwt_verify_keys 为轮换前的密钥, 只用于校验
+func newJWTFromConfig(conf *viper.Viper) (*JWT, error) {
+	kid := conf.GetString("jwt_kid")
+	if kid == "" {
+		kid = "default"
+	}
+
+	key, err := newKeyFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*Key{kid: key}
+
+	for oldKid := range conf.GetStringMap("jwt_verify_keys") {
+		sub := conf.Sub("jwt_verify_keys." + oldKid)
+		if sub == nil {
+			continue
+		}
+
+		old, err := newKeyFromConfig(sub)
+		if err != nil {
+			return nil, err
+		}
+		keys[oldKid] = old
+	}
+
+	return NewJWT(kid, keys)
+}
+
+// 兼容 jwt_ 前缀, jwt_verify_keys 中的密钥可以省略前缀
+func newKeyFromConfig(conf *viper.Viper) (*Key, error) {
+	get := func(key string) string {
+		if v := conf.GetString("jwt_" + key); v != "" {
+			return v
+		}
+		return conf.GetString(key)
+	}
+
+	alg := strings.ToUpper(get("alg"))
+	if alg == "" {
+		alg = "HS256"
+	}
+
+	key := &Key{Alg: alg, Secret: []byte(get("secret"))}
+
+	if file := get("private_key_file"); file != "" {
+		pk, err := LoadRSAPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = pk
+	}
+
+	if file := get("public_key_file"); file != "" {
+		pub, err := LoadRSAPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = pub
+	}
+
+	return key, nil
+}
+
+// 签发 access token 和 refresh token
+func (a *Auth) IssueToken(p *Principal) (*Token, error) {
+	access, err := a.sign(p, TokenTypeAccess, a.AccessExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := a.sign(p, TokenTypeRefresh, a.RefreshExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Token{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(a.AccessExpire / time.Second),
+	}, nil
+}
+
+func (a *Auth) sign(p *Principal, tokenType string, expire time.Duration) (string, error) {
+	if a.jwt == nil {
+		return "", errors.New("auth: jwt is not configured")
+	}
+
+	now := time.Now()
+	return a.jwt.Sign(&Claims{
+		Id:        randomId(),
+		Issuer:    a.Issuer,
+		Subject:   p.Subject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(expire).Unix(),
+		TokenType: tokenType,
+		Roles:     p.Roles,
+		Ext:       p.Ext,
+	})
+}
+
+func (a *Auth) parse(token, tokenType string) (*Claims, error) {
+	if a.jwt == nil {
+		return nil, errors.New("auth: jwt is not configured")
+	}
+
+	claims, err := a.jwt.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType || claims.Subject == "" {
+		return nil, ErrTokenInvalid
+	}
+
+	if a.Issuer != "" && claims.Issuer != a.Issuer {
+		return nil, ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// 校验 access token
+func (a *Auth) VerifyToken(token string) (*Principal, error) {
+	claims, err := a.parse(token, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{Subject: claims.Subject, Roles: claims.Roles, Ext: claims.Ext}, nil
+}
+
+// 使用 refresh token 换取新的令牌, 开启 RefreshRotation 时旧的 refresh token 失效
+// 如果角色等信息有变化, 可以校验后调用 IssueToken 重新签发
+func (a *Auth) RefreshToken(refreshToken string) (*Token, error) {
+	claims, err := a.parse(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.RefreshRotation {
+		if err := a.revoke(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return a.IssueToken(&Principal{Subject: claims.Subject, Roles: claims.Roles, Ext: claims.Ext})
+}
+
+// 注销时使 refresh token 失效
+func (a *Auth) RevokeRefreshToken(refreshToken string) error {
+	claims, err := a.parse(refreshToken, TokenTypeRefresh)
+	if err == ErrTokenExpired {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = a.revoke(claims)
+	if err == ErrTokenRevoked {
+		return nil
+	}
+	return err
+}
+
+// 记录已使用的 jti 直到 token 过期, 已经记录过返回 ErrTokenRevoked
+func (a *Auth) revoke(claims *Claims) error {
+	if a.store == nil {
+		return ErrStoreRequired
+	}
+
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if ttl <= 0 {
+		return ErrTokenExpired
+	}
+
+	ok, err := a.store.SetNx(a.Prefix+"refresh:"+claims.Id, []byte("1"), ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// 创建服务端会话, 返回会话 id
+func (a *Auth) CreateSession(p *Principal) (string, error) {
+	if a.store == nil {
+		return "", ErrStoreRequired
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	sid := randomId()
+	if err := a.store.Set(a.sessionKey(sid), data, a.SessionExpire); err != nil {
+		return "", err
+	}
+
+	return sid, nil
+}
+
+// 获取会话并延长有效期, 会话不存在或已过期返回 ErrSessionNotFound
+func (a *Auth) GetSession(sid string) (*Principal, error) {
+	if a.store == nil {
+		return nil, ErrStoreRequired
+	}
+
+	if sid == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	data, err := a.store.Get(a.sessionKey(sid))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	p := new(Principal)
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+
+	if err := a.store.Expire(a.sessionKey(sid), a.SessionExpire); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (a *Auth) DestroySession(sid string) error {
+	if a.store == nil {
+		return ErrStoreRequired
+	}
+
+	return a.store.Del(a.sessionKey(sid))
+}
+
+func (a *Auth) sessionKey(sid string) string {
+	return a.Prefix + "session:" + sid
+}
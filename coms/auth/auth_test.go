@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// go test -v ./coms/auth
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttl  map[string]time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string][]byte), ttl: make(map[string]time.Time)}
+}
+
+func (s *memStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Now().After(s.ttl[key]) {
+		delete(s.data, key)
+	}
+	return s.data[key], nil
+}
+
+func (s *memStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = value
+	s.ttl[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memStore) SetNx(key string, value []byte, ttl time.Duration) (bool, error) {
+	if v, _ := s.Get(key); v != nil {
+		return false, nil
+	}
+	return true, s.Set(key, value, ttl)
+}
+
+func (s *memStore) Expire(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ttl[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memStore) Del(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, key)
+	return nil
+}
+
+func newTestJWT(t *testing.T, kid string, keys map[string]*Key) *JWT {
+	j, err := NewJWT(kid, keys)
+	if err != nil {
+		t.Fatalf("new jwt err: %s", err)
+	}
+	return j
+}
+
+func TestJWT_SignAndParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key err: %s", err)
+	}
+
+	keys := []*Key{
+		{Alg: "HS256", Secret: []byte("secret")},
+		{Alg: "HS512", Secret: []byte("secret")},
+		{Alg: "RS256", PrivateKey: rsaKey},
+	}
+
+	for _, key := range keys {
+		j := newTestJWT(t, "k1", map[string]*Key{"k1": key})
+
+		token, err := j.Sign(&Claims{Id: "1", Subject: "alice", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+		if err != nil {
+			t.Fatalf("%s sign err: %s", key.Alg, err)
+		}
+
+		claims, err := j.Parse(token)
+		if err != nil || claims.Subject != "alice" {
+			t.Errorf("%s parse got %v, %v", key.Alg, claims, err)
+		}
+
+		// 篡改载荷
+		parts := strings.Split(token, ".")
+		parts[1] = parts[1][:len(parts[1])-2] + "xx"
+		if _, err := j.Parse(strings.Join(parts, ".")); err != ErrTokenInvalid {
+			t.Errorf("%s parse tampered token got %v", key.Alg, err)
+		}
+	}
+
+	// 只有公钥时可以校验, 不能签发
+	pub := newTestJWT(t, "k1", map[string]*Key{"k1": {Alg: "RS256", PublicKey: &rsaKey.PublicKey}})
+	if _, err := pub.Sign(&Claims{Subject: "alice"}); err == nil {
+		t.Error("sign without private key should fail")
+	}
+}
+
+func TestJWT_Expired(t *testing.T) {
+	j := newTestJWT(t, "k1", map[string]*Key{"k1": {Alg: "HS256", Secret: []byte("secret")}})
+
+	token, _ := j.Sign(&Claims{Subject: "alice", ExpiresAt: time.Now().Add(-time.Second).Unix()})
+	if _, err := j.Parse(token); err != ErrTokenExpired {
+		t.Errorf("parse expired token got %v", err)
+	}
+}
+
+func TestJWT_KeyRotation(t *testing.T) {
+	k1 := &Key{Alg: "HS256", Secret: []byte("old")}
+	k2 := &Key{Alg: "HS256", Secret: []byte("new")}
+
+	old := newTestJWT(t, "k1", map[string]*Key{"k1": k1})
+	token, _ := old.Sign(&Claims{Subject: "alice"})
+
+	rotated := newTestJWT(t, "k2", map[string]*Key{"k1": k1, "k2": k2})
+	if _, err := rotated.Parse(token); err != nil {
+		t.Errorf("parse token signed by old key err: %s", err)
+	}
+
+	removed := newTestJWT(t, "k2", map[string]*Key{"k2": k2})
+	if _, err := removed.Parse(token); err != ErrTokenInvalid {
+		t.Errorf("parse token signed by removed key got %v", err)
+	}
+}
+
+func TestAuth_RefreshToken(t *testing.T) {
+	j := newTestJWT(t, "k1", map[string]*Key{"k1": {Alg: "HS256", Secret: []byte("secret")}})
+	a := New(j, newMemStore())
+	a.Issuer = "test"
+
+	token, err := a.IssueToken(&Principal{Subject: "alice", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("issue token err: %s", err)
+	}
+
+	p, err := a.VerifyToken(token.AccessToken)
+	if err != nil || p.Subject != "alice" || len(p.Roles) != 1 {
+		t.Fatalf("verify token got %v, %v", p, err)
+	}
+
+	// refresh token 不能当作 access token 使用
+	if _, err := a.VerifyToken(token.RefreshToken); err != ErrTokenInvalid {
+		t.Errorf("verify refresh token as access token got %v", err)
+	}
+
+	refreshed, err := a.RefreshToken(token.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh token err: %s", err)
+	}
+
+	if _, err := a.RefreshToken(token.RefreshToken); err != ErrTokenRevoked {
+		t.Errorf("reuse refresh token got %v", err)
+	}
+
+	if err := a.RevokeRefreshToken(refreshed.RefreshToken); err != nil {
+		t.Fatalf("revoke refresh token err: %s", err)
+	}
+
+	if _, err := a.RefreshToken(refreshed.RefreshToken); err != ErrTokenRevoked {
+		t.Errorf("use revoked refresh token got %v", err)
+	}
+}
+
+func TestAuth_Session(t *testing.T) {
+	a := New(nil, newMemStore())
+	a.SessionExpire = 100 * time.Millisecond
+
+	sid, err := a.CreateSession(&Principal{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("create session err: %s", err)
+	}
+
+	// 持续访问时会话不过期
+	for i := 0; i < 3; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if p, err := a.GetSession(sid); err != nil || p.Subject != "alice" {
+			t.Fatalf("get session got %v, %v", p, err)
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if _, err := a.GetSession(sid); err != ErrSessionNotFound {
+		t.Errorf("get expired session got %v", err)
+	}
+
+	sid, _ = a.CreateSession(&Principal{Subject: "bob"})
+	if err := a.DestroySession(sid); err != nil {
+		t.Fatalf("destroy session err: %s", err)
+	}
+	if _, err := a.GetSession(sid); err != ErrSessionNotFound {
+		t.Errorf("get destroyed session got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	ErrTokenInvalid = errors.New("auth: token is invalid")
+	ErrTokenExpired = errors.New("auth: token is expired")
+	ErrTokenRevoked = errors.New("auth: token is revoked")
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// jwt 载荷, Roles 和 Ext 来自 Principal
+type Claims struct {
+	Id        string                 `json:"jti"`
+	Issuer    string                 `json:"iss,omitempty"`
+	Subject   string                 `json:"sub"`
+	IssuedAt  int64                  `json:"iat"`
+	NotBefore int64                  `json:"nbf,omitempty"`
+	ExpiresAt int64                  `json:"exp"`
+	TokenType string                 `json:"token_type"`
+	Roles     []string               `json:"roles,omitempty"`
+	Ext       map[string]interface{} `json:"ext,omitempty"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// 签名密钥, HS 系列使用共享密钥, RS 系列签发需要私钥, 校验只需要公钥
+type Key struct {
+	Alg        string
+	Secret     []byte
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+func algHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "HS256", "RS256":
+		return crypto.SHA256, true
+	case "HS384", "RS384":
+		return crypto.SHA384, true
+	case "HS512", "RS512":
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+func (k *Key) validate() error {
+	if _, ok := algHash(k.Alg); !ok {
+		return fmt.Errorf("unsupported jwt alg %s", k.Alg)
+	}
+
+	if strings.HasPrefix(k.Alg, "HS") {
+		if len(k.Secret) == 0 {
+			return fmt.Errorf("jwt secret is empty for alg %s", k.Alg)
+		}
+		return nil
+	}
+
+	if k.PublicKey == nil && k.PrivateKey != nil {
+		k.PublicKey = &k.PrivateKey.PublicKey
+	}
+	if k.PublicKey == nil {
+		return fmt.Errorf("jwt rsa key is empty for alg %s", k.Alg)
+	}
+	return nil
+}
+
+func (k *Key) sign(data []byte) ([]byte, error) {
+	h, _ := algHash(k.Alg)
+	if strings.HasPrefix(k.Alg, "HS") {
+		mac := hmac.New(h.New, k.Secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	}
+
+	if k.PrivateKey == nil {
+		return nil, errors.New("auth: rsa private key is required to sign token")
+	}
+
+	hasher := h.New()
+	hasher.Write(data)
+	return rsa.SignPKCS1v15(rand.Reader, k.PrivateKey, h, hasher.Sum(nil))
+}
+
+func (k *Key) verify(data, sig []byte) bool {
+	h, _ := algHash(k.Alg)
+	if strings.HasPrefix(k.Alg, "HS") {
+		mac := hmac.New(h.New, k.Secret)
+		mac.Write(data)
+		return hmac.Equal(sig, mac.Sum(nil))
+	}
+
+	hasher := h.New()
+	hasher.Write(data)
+	return rsa.VerifyPKCS1v15(k.PublicKey, h, hasher.Sum(nil), sig) == nil
+}
+
+// 读取 PEM 格式的 rsa 私钥, 支持 PKCS1 和 PKCS8
+func LoadRSAPrivateKey(file string) (*rsa.PrivateKey, error) {
+	block, err := readPem(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a rsa private key", file)
+	}
+	return rsaKey, nil
+}
+
+// 读取 PEM 格式的 rsa 公钥, 支持 PKIX, PKCS1 和证书
+func LoadRSAPublicKey(file string) (*rsa.PublicKey, error) {
+	block, err := readPem(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a rsa public key", file)
+	}
+	return rsaKey, nil
+}
+
+func readPem(file string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a pem file", file)
+	}
+	return block, nil
+}
+
+// 签发和校验 jwt, 使用 kid 区分密钥, 轮换密钥后旧密钥仍可用于校验
+type JWT struct {
+	kid  string
+	keys map[string]*Key
+}
+
+// kid 为签发使用的密钥, keys 为全部可用于校验的密钥
+func NewJWT(kid string, keys map[string]*Key) (*JWT, error) {
+	if _, ok := keys[kid]; !ok {
+		return nil, fmt.Errorf("jwt key %s is not found", kid)
+	}
+
+	for id, k := range keys {
+		if err := k.validate(); err != nil {
+			return nil, fmt.Errorf("jwt key %s: %s", id, err)
+		}
+	}
+
+	return &JWT{kid: kid, keys: keys}, nil
+}
+
+func (j *JWT) Sign(claims *Claims) (string, error) {
+	key := j.keys[j.kid]
+
+	header, err := json.Marshal(jwtHeader{Alg: key.Alg, Typ: "JWT", Kid: j.kid})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+
+	sig, err := key.sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// 校验签名和有效期, 签名不对返回 ErrTokenInvalid, 过期返回 ErrTokenExpired
+func (j *JWT) Parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid
+	}
+
+	enc := base64.RawURLEncoding
+
+	headerBytes, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	kid := header.Kid
+	if kid == "" {
+		kid = j.kid
+	}
+
+	key, ok := j.keys[kid]
+	// 不接受 none 以及与密钥不一致的算法
+	if !ok || key.Alg != header.Alg {
+		return nil, ErrTokenInvalid
+	}
+
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil || !key.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, ErrTokenInvalid
+	}
+
+	payload, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	claims := new(Claims)
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	now := time.Now().Unix()
+	if claims.ExpiresAt > 0 && now >= claims.ExpiresAt {
+		return claims, ErrTokenExpired
+	}
+	if claims.NotBefore > 0 && now < claims.NotBefore {
+		return claims, ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
+func randomId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/rbac"
+)
+
+const PrincipalKey = "__AuthPrincipal__"
+
+// 认证中间件写入的主体
+func GetPrincipal(c *yago.Ctx) (*Principal, bool) {
+	v, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil, false
+	}
+
+	p, ok := v.(*Principal)
+	return p, ok
+}
+
+// 同时写入 rbac.SubjectKey, 之后可以直接使用 rbac 中间件
+func SetPrincipal(c *yago.Ctx, p *Principal) {
+	c.Set(PrincipalKey, p)
+	c.Set(rbac.SubjectKey, p.Subject)
+}
+
+// 依次从 Authorization: Bearer 和会话 cookie 中认证, 失败时返回 ErrNotLogin
+// eg. yago.NewHttpGroupRouter("/admin", auth.Ins().Middleware(), rbac.Ins().Middleware())
+func (a *Auth) Middleware() yago.HttpHandlerFunc {
+	return func(c *yago.Ctx) {
+		p, ok := a.Authenticate(c)
+		if !ok {
+			c.AbortWithE(yago.ErrNotLogin)
+			return
+		}
+
+		SetPrincipal(c, p)
+	}
+}
+
+// 认证请求, 不中断请求, 适用于登录可选的接口
+func (a *Auth) Authenticate(c *yago.Ctx) (*Principal, bool) {
+	if token := bearerToken(c); token != "" && a.jwt != nil {
+		p, err := a.VerifyToken(token)
+		return p, err == nil
+	}
+
+	if a.store == nil {
+		return nil, false
+	}
+
+	sid, err := c.Cookie(a.Cookie.Name)
+	if err != nil || sid == "" {
+		return nil, false
+	}
+
+	p, err := a.GetSession(sid)
+	return p, err == nil
+}
+
+func bearerToken(c *yago.Ctx) string {
+	h := c.GetHeader("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// 创建会话并写入 cookie, cookie 不设置过期时间, 有效期由服务端控制
+func (a *Auth) Login(c *yago.Ctx, p *Principal) (string, error) {
+	sid, err := a.CreateSession(p)
+	if err != nil {
+		return "", err
+	}
+
+	c.SetCookie(a.Cookie.Name, sid, 0, a.Cookie.Path, a.Cookie.Domain, a.Cookie.Secure, a.Cookie.HttpOnly)
+	SetPrincipal(c, p)
+
+	return sid, nil
+}
+
+// 销毁会话并清除 cookie
+func (a *Auth) Logout(c *yago.Ctx) error {
+	sid, err := c.Cookie(a.Cookie.Name)
+	if err != nil || sid == "" {
+		return nil
+	}
+
+	c.SetCookie(a.Cookie.Name, "", -1, a.Cookie.Path, a.Cookie.Domain, a.Cookie.Secure, a.Cookie.HttpOnly)
+
+	return a.DestroySession(sid)
+}
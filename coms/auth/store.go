@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/hulklab/yago/coms/rds"
+)
+
+// 会话和 refresh token 状态的存储, 找不到 key 时 Get 返回 nil, nil
+type Store interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	// key 不存在时写入, 返回是否写入成功
+	SetNx(key string, value []byte, ttl time.Duration) (bool, error)
+	Expire(key string, ttl time.Duration) error
+	Del(key string) error
+}
+
+type redisStore struct {
+	rdsId string
+}
+
+// 使用 rds 组件存储
+func NewRedisStore(rdsId string) Store {
+	return &redisStore{rdsId: rdsId}
+}
+
+func (s *redisStore) Get(key string) ([]byte, error) {
+	b, err := redis.Bytes(rds.Ins(s.rdsId).Get(key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (s *redisStore) Set(key string, value []byte, ttl time.Duration) error {
+	_, err := rds.Ins(s.rdsId).Set(key, value, "PX", ttl.Milliseconds())
+	return err
+}
+
+func (s *redisStore) SetNx(key string, value []byte, ttl time.Duration) (bool, error) {
+	_, err := redis.String(rds.Ins(s.rdsId).Set(key, value, "PX", ttl.Milliseconds(), "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *redisStore) Expire(key string, ttl time.Duration) error {
+	_, err := rds.Ins(s.rdsId).PExpire(key, ttl.Milliseconds())
+	return err
+}
+
+func (s *redisStore) Del(key string) error {
+	_, err := rds.Ins(s.rdsId).Del(key)
+	return err
+}
@@ -1,6 +1,8 @@
 package es
 
 import (
+	"context"
+	"fmt"
 	"log"
 
 	"github.com/hulklab/yago"
@@ -83,3 +85,17 @@ func Ins(id ...string) *Elastic {
 
 	return v.(*Elastic)
 }
+
+// 实现 yago.HealthChecker, 集群状态为 red 时不健康
+func (e *Elastic) HealthCheck(ctx context.Context) error {
+	res, err := e.ClusterHealth().Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.Status == "red" {
+		return fmt.Errorf("elastic cluster %s status is red", res.ClusterName)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package etcd
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
@@ -35,6 +36,12 @@ func Ins(id ...string) *Etcd {
 	return client
 }
 
+// 实现 yago.HealthChecker
+func (e *Etcd) HealthCheck(ctx context.Context) error {
+	_, err := e.Get(ctx, "health")
+	return err
+}
+
 // init for etcd
 func initEtcdConn(name string) *Etcd {
 	endpoints := yago.Config.GetStringSlice(name + ".endpoints")
@@ -1,9 +1,11 @@
 package kafka
 
 import (
+	"context"
 	"errors"
 	"log"
 	"sync"
+	"sync/atomic"
 
 	"github.com/Shopify/sarama"
 	cluster "github.com/bsm/sarama-cluster"
@@ -16,7 +18,11 @@ type Kafka struct {
 	config        *cluster.Config
 	asyncProducer *AsyncProducer
 	syncProducer  *SyncProducer
-	mu            sync.Mutex
+	// 健康检查使用的 client, 第一次检查时创建
+	client sarama.Client
+	// 健康检查是否正在进行
+	probing int32
+	mu      sync.Mutex
 }
 
 // 返回 kafka 组件单例
@@ -66,9 +72,72 @@ func (q *Kafka) Close() error {
 	if q.syncProducer != nil {
 		q.syncProducer.close()
 	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.client != nil {
+		_ = q.client.Close()
+		q.client = nil
+	}
 	return nil
 }
 
+// 实现 yago.HealthChecker, 通过组件的 client 刷新 metadata, 检查是否能连接到 broker
+// 上一次检查还没有返回时直接返回错误, broker 不可用时不会堆积连接
+func (q *Kafka) HealthCheck(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&q.probing, 0, 1) {
+		return errors.New("kafka: previous health check is still running")
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		defer atomic.StoreInt32(&q.probing, 0)
+
+		client, err := q.healthClient()
+		if err != nil {
+			done <- err
+			return
+		}
+
+		if err := client.RefreshMetadata(); err != nil {
+			done <- err
+			return
+		}
+
+		if len(client.Brokers()) == 0 {
+			done <- errors.New("kafka: no available broker")
+			return
+		}
+
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Kafka) healthClient() (sarama.Client, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.client != nil {
+		return q.client, nil
+	}
+
+	client, err := sarama.NewClient(q.connect, &q.config.Config)
+	if err != nil {
+		return nil, err
+	}
+	q.client = client
+
+	return client, nil
+}
+
 type Consumer struct {
 	conn      *cluster.Consumer
 	closeChan chan bool
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"github.com/hulklab/yago/libs/tracelib"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,3 +24,31 @@ func (h *Hook) Fire(entry *logrus.Entry) error {
 func (h *Hook) Levels() []logrus.Level {
 	return logrus.AllLevels
 }
+
+// 使用 logger.Ins().WithContext(ctx) 时, 将 ctx 中的 trace_id 和 span_id 写入日志
+type TraceHook struct{}
+
+func (h *TraceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	traceId := tracelib.TraceIDFromContext(entry.Context)
+	if traceId == "" {
+		return nil
+	}
+
+	if _, ok := entry.Data["trace_id"]; !ok {
+		entry.Data["trace_id"] = traceId
+	}
+
+	if _, ok := entry.Data["span_id"]; !ok {
+		entry.Data["span_id"] = tracelib.SpanIDFromContext(entry.Context)
+	}
+
+	return nil
+}
+
+func (h *TraceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
@@ -58,6 +58,9 @@ func Ins(id ...string) *Logger {
 			Compress:   compress,
 		}
 
+		// 日志带上 trace_id, span_id
+		val.AddHook(new(TraceHook))
+
 		if stdoutEnable {
 			val.AddHook(NewStdoutHook())
 		}
@@ -57,6 +57,11 @@ func Ins(id ...string) *Mgo {
 	return v.(*Mgo)
 }
 
+// 实现 yago.HealthChecker
+func (m *Mgo) HealthCheck(ctx context.Context) error {
+	return m.Client().Ping(ctx, nil)
+}
+
 func defCtx() context.Context {
 	return context.Background()
 }
@@ -13,6 +13,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/hulklab/yago"
 	"github.com/hulklab/yago/coms/logger"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/sirupsen/logrus"
 	"xorm.io/xorm"
 	xormLog "xorm.io/xorm/log"
@@ -22,6 +23,11 @@ type Orm struct {
 	*xorm.Engine
 }
 
+// 实现 yago.HealthChecker
+func (o *Orm) HealthCheck(ctx context.Context) error {
+	return o.PingContext(ctx)
+}
+
 // 扩展了一个事务功能
 func (o *Orm) Transactional(f func(session *xorm.Session) error, opts ...OrmOption) (err error) {
 	session := o.NewSession()
@@ -226,10 +232,15 @@ func Ins(id ...string) *Orm {
 				orm.SetLogger(ctxLogger)
 				orm.ShowSQL(showLog.(bool))
 			} else {
-				orm.SetLogger(getLogger(showLog.(bool)))
+				orm.SetLogger(newSqlLogger(getLogger(showLog.(bool))))
 			}
 		}
 
+		// 连接池指标
+		if yago.MetricsEnabled() {
+			yago.RegisterMetrics(collectors.NewDBStatsCollector(orm.DB().DB, name))
+		}
+
 		return orm
 	})
 
@@ -274,3 +285,66 @@ func (l *Logger) ShowSQL(show ...bool) {
 func (l *Logger) IsShowSQL() bool {
 	return l.show
 }
+
+// 实现 xorm ContextLogger, sql 日志带上 ctx 中的 trace_id, span_id
+// Logger 同时满足 xorm Logger, SetLogger 会使用不带 ctx 的 adapter, 所以单独定义
+type sqlLogger struct {
+	logger *Logger
+}
+
+func newSqlLogger(l *Logger) *sqlLogger {
+	return &sqlLogger{logger: l}
+}
+
+func (l *sqlLogger) BeforeSQL(ctx xormLog.LogContext) {}
+
+func (l *sqlLogger) AfterSQL(ctx xormLog.LogContext) {
+	entry := l.logger.Entry
+
+	var sessionPart string
+	if ctx.Ctx != nil {
+		entry = entry.WithContext(ctx.Ctx)
+
+		if key, ok := ctx.Ctx.Value(xormLog.SessionIDKey).(string); ok {
+			sessionPart = fmt.Sprintf(" [%s]", key)
+		}
+	}
+
+	if ctx.ExecuteTime > 0 {
+		entry.Infof("[SQL]%s %s %v - %v", sessionPart, ctx.SQL, ctx.Args, ctx.ExecuteTime)
+	} else {
+		entry.Infof("[SQL]%s %s %v", sessionPart, ctx.SQL, ctx.Args)
+	}
+}
+
+func (l *sqlLogger) Debugf(format string, v ...interface{}) {
+	l.logger.Debugf(format, v...)
+}
+
+func (l *sqlLogger) Errorf(format string, v ...interface{}) {
+	l.logger.Errorf(format, v...)
+}
+
+func (l *sqlLogger) Infof(format string, v ...interface{}) {
+	l.logger.Infof(format, v...)
+}
+
+func (l *sqlLogger) Warnf(format string, v ...interface{}) {
+	l.logger.Warnf(format, v...)
+}
+
+func (l *sqlLogger) Level() xormLog.LogLevel {
+	return l.logger.Level()
+}
+
+func (l *sqlLogger) SetLevel(c xormLog.LogLevel) {
+	l.logger.SetLevel(c)
+}
+
+func (l *sqlLogger) ShowSQL(show ...bool) {
+	l.logger.ShowSQL(show...)
+}
+
+func (l *sqlLogger) IsShowSQL() bool {
+	return l.logger.IsShowSQL()
+}
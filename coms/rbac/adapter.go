@@ -0,0 +1,146 @@
+package rbac
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hulklab/yago/coms/orm"
+)
+
+// 权限策略, 主体可以是用户或角色
+type Policy struct {
+	Subject  string
+	Resource string
+	Action   string
+}
+
+// 角色继承, 成员可以是用户或角色
+type Grouping struct {
+	Member string
+	Role   string
+}
+
+// 策略来源
+type Adapter interface {
+	LoadPolicy() ([]Policy, []Grouping, error)
+}
+
+// 解析一行规则, 与 casbin 格式相同
+// p, admin, /api/users/*, GET
+// g, alice, admin
+func parseRule(fields []string, policies []Policy, groupings []Grouping) ([]Policy, []Grouping, error) {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	switch fields[0] {
+	case "p":
+		if len(fields) != 4 {
+			return policies, groupings, fmt.Errorf("policy rule must be like p, subject, resource, action: %s", strings.Join(fields, ", "))
+		}
+		policies = append(policies, Policy{Subject: fields[1], Resource: fields[2], Action: fields[3]})
+	case "g":
+		if len(fields) != 3 {
+			return policies, groupings, fmt.Errorf("grouping rule must be like g, member, role: %s", strings.Join(fields, ", "))
+		}
+		groupings = append(groupings, Grouping{Member: fields[1], Role: fields[2]})
+	default:
+		return policies, groupings, fmt.Errorf("unknown rule type: %s", fields[0])
+	}
+
+	return policies, groupings, nil
+}
+
+// 从 csv 文件加载, # 开头为注释
+type FileAdapter struct {
+	Path string
+}
+
+func NewFileAdapter(path string) *FileAdapter {
+	return &FileAdapter{Path: path}
+}
+
+func (a *FileAdapter) LoadPolicy() ([]Policy, []Grouping, error) {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	policies := make([]Policy, 0)
+	groupings := make([]Grouping, 0)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		policies, groupings, err = parseRule(strings.Split(line, ","), policies, groupings)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return policies, groupings, nil
+}
+
+// 规则表, 与 casbin 的 casbin_rule 表兼容
+//
+//	CREATE TABLE `rbac_rule` (
+//	  `id` int unsigned NOT NULL AUTO_INCREMENT,
+//	  `ptype` varchar(8) NOT NULL DEFAULT '',
+//	  `v0` varchar(128) NOT NULL DEFAULT '',
+//	  `v1` varchar(128) NOT NULL DEFAULT '',
+//	  `v2` varchar(128) NOT NULL DEFAULT '',
+//	  PRIMARY KEY (`id`)
+//	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+type Rule struct {
+	Id    int64  `xorm:"pk autoincr"`
+	Ptype string `xorm:"ptype"`
+	V0    string `xorm:"v0"`
+	V1    string `xorm:"v1"`
+	V2    string `xorm:"v2"`
+}
+
+// 从数据库加载
+type OrmAdapter struct {
+	OrmId string
+	Table string
+}
+
+func NewOrmAdapter(ormId, table string) *OrmAdapter {
+	return &OrmAdapter{OrmId: ormId, Table: table}
+}
+
+func (a *OrmAdapter) LoadPolicy() ([]Policy, []Grouping, error) {
+	rules := make([]*Rule, 0)
+	if err := orm.Ins(a.OrmId).Table(a.Table).Asc("id").Find(&rules); err != nil {
+		return nil, nil, err
+	}
+
+	policies := make([]Policy, 0)
+	groupings := make([]Grouping, 0)
+
+	var err error
+	for _, r := range rules {
+		fields := []string{r.Ptype, r.V0, r.V1}
+		if r.Ptype == "p" {
+			fields = append(fields, r.V2)
+		}
+
+		policies, groupings, err = parseRule(fields, policies, groupings)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return policies, groupings, nil
+}
@@ -0,0 +1,130 @@
+package rbac
+
+import (
+	"github.com/hulklab/yago"
+)
+
+// 认证中间件将用户标识写入此 key, 作为授权的主体
+// eg. c.Set(rbac.SubjectKey, userId)
+const SubjectKey = "__RbacSubject__"
+
+type Permission struct {
+	Resource string
+	Action   string
+}
+
+// 路由需要的角色和权限, 同时声明时需要都满足
+type Requirement struct {
+	// 拥有其中任一角色即可
+	Roles []string
+	// 需要拥有全部权限
+	Permissions []Permission
+}
+
+func (r Requirement) RbacRequirement() Requirement {
+	return r
+}
+
+// 路由 metadata 实现此接口声明需要的角色和权限, 可以直接使用 Requirement 或在 metadata 中嵌入 Requirement
+// eg. ghttp.Root.Post("/user/delete", h.DeleteAction).WithMetadata(rbac.Requirement{Roles: []string{"admin"}})
+type RequirementProvider interface {
+	RbacRequirement() Requirement
+}
+
+type MiddlewareOption func(m *middleware)
+
+// 自定义获取主体的方式, 默认从 SubjectKey 取
+func WithSubjectFunc(f func(c *yago.Ctx) string) MiddlewareOption {
+	return func(m *middleware) {
+		m.subject = f
+	}
+}
+
+type middleware struct {
+	rbac    *Rbac
+	subject func(c *yago.Ctx) string
+}
+
+// 需要在认证中间件之后使用, 没有主体时返回 ErrNotLogin, 没有权限时返回 ErrForbidden
+// eg. yago.NewHttpGroupRouter("/admin", auth, rbac.Ins().Middleware())
+func (r *Rbac) Middleware(opts ...MiddlewareOption) yago.HttpHandlerFunc {
+	m := &middleware{
+		rbac: r,
+		subject: func(c *yago.Ctx) string {
+			return c.GetString(SubjectKey)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m.handle
+}
+
+func (m *middleware) handle(c *yago.Ctx) {
+	req, declared := getRequirement(c)
+	if !declared && !m.rbac.RoutePolicy {
+		return
+	}
+
+	subject := m.subject(c)
+	if subject == "" {
+		c.AbortWithE(yago.ErrNotLogin)
+		return
+	}
+
+	var allowed bool
+	if declared {
+		allowed = m.rbac.Check(subject, req)
+	} else {
+		allowed = m.rbac.Enforce(subject, c.FullPath(), c.Request.Method)
+	}
+
+	if !allowed {
+		c.AbortWithE(yago.ErrForbidden)
+	}
+}
+
+func getRequirement(c *yago.Ctx) (Requirement, bool) {
+	r, ok := c.GetHttpRouter()
+	if !ok {
+		return Requirement{}, false
+	}
+
+	p, ok := r.Metadata.(RequirementProvider)
+	if !ok {
+		return Requirement{}, false
+	}
+
+	req := p.RbacRequirement()
+	if len(req.Roles) == 0 && len(req.Permissions) == 0 {
+		return req, false
+	}
+
+	return req, true
+}
+
+// 主体是否满足声明的角色和权限
+func (r *Rbac) Check(subject string, req Requirement) bool {
+	if len(req.Roles) > 0 {
+		matched := false
+		for _, role := range req.Roles {
+			if r.HasRole(subject, role) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, p := range req.Permissions {
+		if !r.Enforce(subject, p.Resource, p.Action) {
+			return false
+		}
+	}
+
+	return true
+}
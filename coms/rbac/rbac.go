@@ -0,0 +1,305 @@
+package rbac
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+)
+
+type Rbac struct {
+	name    string
+	adapter Adapter
+
+	mu sync.RWMutex
+	// 主体 => 策略
+	policies map[string][]Policy
+	// 成员 => 直接继承的角色
+	roles map[string][]string
+	// 规则内容, 重新加载时内容不变则不清空缓存
+	fingerprint string
+	// 规则变化时加 1, 旧规则算出的决策不再写入缓存
+	generation uint64
+
+	cacheMu sync.Mutex
+	cache   map[string]bool
+	// 缓存中的决策对应的规则版本
+	cacheGeneration uint64
+	cacheSize       int
+
+	// 未声明 Requirement 的路由是否按 (用户, 路由模板, 请求方法) 检查策略
+	RoutePolicy bool
+
+	stop chan struct{}
+	once sync.Once
+}
+
+func Ins(id ...string) *Rbac {
+	var name string
+
+	if len(id) == 0 {
+		name = "rbac"
+	} else if len(id) > 0 {
+		name = id[0]
+	}
+
+	v := yago.Component.Ins(name, func() interface{} {
+		conf := yago.Config.Sub(name)
+		if conf == nil {
+			log.Fatalf("Fatal error: rbac config %s is not found", name)
+		}
+
+		var adapter Adapter
+		switch conf.GetString("adapter") {
+		case "file", "":
+			file := conf.GetString("file")
+			if file == "" {
+				log.Fatalf("Fatal error: rbac policy file is empty")
+			}
+			adapter = NewFileAdapter(file)
+		case "orm":
+			ormId := conf.GetString("orm_instance_id")
+			if ormId == "" {
+				ormId = "db"
+			}
+			table := conf.GetString("table")
+			if table == "" {
+				table = "rbac_rule"
+			}
+			adapter = NewOrmAdapter(ormId, table)
+		default:
+			log.Fatalf("Fatal error: unsupported rbac adapter %s", conf.GetString("adapter"))
+		}
+
+		val, err := New(adapter, conf.GetInt("cache_size"))
+		if err != nil {
+			log.Fatalf("Fatal error: load rbac policy err: %s", err)
+		}
+		val.name = name
+		val.RoutePolicy = conf.GetBool("route_policy")
+
+		if conf.IsSet("reload_interval") {
+			val.StartAutoReload(conf.GetDuration("reload_interval"))
+		}
+
+		return val
+	})
+
+	return v.(*Rbac)
+}
+
+// cacheSize 为决策缓存条数, 为 0 时使用 10000, 小于 0 时不缓存
+func New(adapter Adapter, cacheSize int) (*Rbac, error) {
+	if cacheSize == 0 {
+		cacheSize = 10000
+	}
+
+	r := &Rbac{
+		name:      "rbac",
+		adapter:   adapter,
+		cache:     make(map[string]bool),
+		cacheSize: cacheSize,
+		stop:      make(chan struct{}),
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// 重新加载策略, 失败时保留原有策略
+func (r *Rbac) Reload() error {
+	ps, gs, err := r.adapter.LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	policies := make(map[string][]Policy)
+	var b strings.Builder
+	for _, p := range ps {
+		policies[p.Subject] = append(policies[p.Subject], p)
+		b.WriteString("p\x00" + p.Subject + "\x00" + p.Resource + "\x00" + p.Action + "\n")
+	}
+
+	roles := make(map[string][]string)
+	for _, g := range gs {
+		roles[g.Member] = append(roles[g.Member], g.Role)
+		b.WriteString("g\x00" + g.Member + "\x00" + g.Role + "\n")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fingerprint == b.String() {
+		return nil
+	}
+
+	r.policies = policies
+	r.roles = roles
+	r.fingerprint = b.String()
+	r.generation++
+	r.clearCache(r.generation)
+
+	return nil
+}
+
+// 定时重新加载, 加载失败时记录日志
+func (r *Rbac) StartAutoReload(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if err := r.Reload(); err != nil {
+					logger.Ins().Category("rbac").Errorf("%s reload policy err: %s", r.name, err)
+				}
+			}
+		}
+	}()
+}
+
+// 实现 io.Closer, app 关闭时停止自动加载
+func (r *Rbac) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	return nil
+}
+
+// 主体拥有的全部角色, 包括继承的角色
+func (r *Rbac) GetRoles(subject string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.getRoles(subject)
+}
+
+func (r *Rbac) getRoles(subject string) []string {
+	visited := map[string]bool{subject: true}
+	queue := []string{subject}
+	roles := make([]string, 0)
+
+	for len(queue) > 0 {
+		member := queue[0]
+		queue = queue[1:]
+
+		for _, role := range r.roles[member] {
+			if visited[role] {
+				continue
+			}
+			visited[role] = true
+			roles = append(roles, role)
+			queue = append(queue, role)
+		}
+	}
+
+	return roles
+}
+
+func (r *Rbac) HasRole(subject, role string) bool {
+	for _, v := range r.GetRoles(subject) {
+		if v == role {
+			return true
+		}
+	}
+	return false
+}
+
+// 主体或其角色是否有权限对资源执行操作
+// 资源支持 * 结尾的前缀匹配, * 匹配所有资源和操作, 操作不区分大小写
+func (r *Rbac) Enforce(subject, resource, action string) bool {
+	key := subject + "\x00" + resource + "\x00" + action
+	if allowed, ok := r.getCache(key); ok {
+		return allowed
+	}
+
+	r.mu.RLock()
+	allowed := r.enforce(subject, resource, action)
+	generation := r.generation
+	r.mu.RUnlock()
+
+	r.setCache(key, allowed, generation)
+
+	return allowed
+}
+
+func (r *Rbac) enforce(subject, resource, action string) bool {
+	subjects := append([]string{subject}, r.getRoles(subject)...)
+
+	for _, s := range subjects {
+		for _, p := range r.policies[s] {
+			if matchResource(p.Resource, resource) && matchAction(p.Action, action) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchResource(pattern, resource string) bool {
+	if pattern == "*" || pattern == resource {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(resource, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return false
+}
+
+func matchAction(pattern, action string) bool {
+	return pattern == "*" || strings.EqualFold(pattern, action)
+}
+
+func (r *Rbac) getCache(key string) (bool, bool) {
+	if r.cacheSize < 0 {
+		return false, false
+	}
+
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	allowed, ok := r.cache[key]
+	return allowed, ok
+}
+
+// 超出容量时整体清空, 决策算出后规则已经重新加载时不写入
+func (r *Rbac) setCache(key string, allowed bool, generation uint64) {
+	if r.cacheSize < 0 {
+		return
+	}
+
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+
+	if generation != r.cacheGeneration {
+		return
+	}
+
+	if len(r.cache) >= r.cacheSize {
+		r.cache = make(map[string]bool)
+	}
+	r.cache[key] = allowed
+}
+
+func (r *Rbac) clearCache(generation uint64) {
+	r.cacheMu.Lock()
+	r.cache = make(map[string]bool)
+	r.cacheGeneration = generation
+	r.cacheMu.Unlock()
+}
@@ -0,0 +1,226 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// go test -v ./coms/rbac
+
+const testPolicy = `
+# admin 继承 editor
+p, editor, /api/articles/*, GET
+p, editor, /api/articles/:id, PUT
+p, admin, /api/users/*, *
+p, article, publish, *
+g, editor, article
+g, admin, editor
+g, alice, admin
+g, bob, editor
+`
+
+func newTestRbac(t *testing.T, policy string) (*Rbac, string) {
+	file := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(file, []byte(policy), 0644); err != nil {
+		t.Fatalf("write policy file err: %s", err)
+	}
+
+	r, err := New(NewFileAdapter(file), 0)
+	if err != nil {
+		t.Fatalf("new rbac err: %s", err)
+	}
+
+	return r, file
+}
+
+func TestRbac_Enforce(t *testing.T) {
+	r, _ := newTestRbac(t, testPolicy)
+
+	cases := []struct {
+		subject, resource, action string
+		allowed                   bool
+	}{
+		{"bob", "/api/articles/:id", "GET", true},
+		{"bob", "/api/articles/:id", "put", true},
+		{"bob", "/api/articles/:id", "DELETE", false},
+		{"bob", "/api/users/:id", "GET", false},
+		{"bob", "publish", "POST", true},
+		{"alice", "/api/users/:id", "DELETE", true},
+		{"alice", "/api/articles/:id", "GET", true},
+		{"carol", "/api/articles/:id", "GET", false},
+	}
+
+	for _, c := range cases {
+		// 第二次命中缓存
+		for i := 0; i < 2; i++ {
+			if got := r.Enforce(c.subject, c.resource, c.action); got != c.allowed {
+				t.Errorf("enforce %s %s %s got %v, want %v", c.subject, c.resource, c.action, got, c.allowed)
+			}
+		}
+	}
+}
+
+func TestRbac_GetRoles(t *testing.T) {
+	r, _ := newTestRbac(t, testPolicy+"g, article, admin\n")
+
+	// 循环继承不会死循环
+	roles := r.GetRoles("alice")
+	if len(roles) != 3 {
+		t.Errorf("alice roles got %v", roles)
+	}
+
+	if !r.HasRole("bob", "article") {
+		t.Error("bob should has role article")
+	}
+}
+
+func TestRbac_Check(t *testing.T) {
+	r, _ := newTestRbac(t, testPolicy)
+
+	req := Requirement{
+		Roles:       []string{"admin", "editor"},
+		Permissions: []Permission{{Resource: "/api/articles/1", Action: "GET"}},
+	}
+
+	if !r.Check("bob", req) {
+		t.Error("bob should pass")
+	}
+
+	if r.Check("carol", req) {
+		t.Error("carol should not pass")
+	}
+
+	if r.Check("bob", Requirement{Roles: []string{"admin"}}) {
+		t.Error("bob is not admin")
+	}
+}
+
+func TestRbac_Reload(t *testing.T) {
+	r, file := newTestRbac(t, testPolicy)
+
+	if !r.Enforce("bob", "/api/articles/1", "GET") {
+		t.Fatal("bob should be allowed before reload")
+	}
+
+	if err := os.WriteFile(file, []byte("p, editor, /api/articles/*, PUT\ng, bob, editor\n"), 0644); err != nil {
+		t.Fatalf("write policy file err: %s", err)
+	}
+
+	if err := r.Reload(); err != nil {
+		t.Fatalf("reload err: %s", err)
+	}
+
+	if r.Enforce("bob", "/api/articles/1", "GET") {
+		t.Error("bob should be denied after reload")
+	}
+
+	// 加载失败时保留原有策略
+	if err := os.WriteFile(file, []byte("x, bad\n"), 0644); err != nil {
+		t.Fatalf("write policy file err: %s", err)
+	}
+
+	if err := r.Reload(); err == nil {
+		t.Error("reload bad policy should fail")
+	}
+
+	if !r.Enforce("bob", "/api/articles/1", "PUT") {
+		t.Error("bob should keep old policy")
+	}
+}
+
+// 在内存中切换策略
+type switchAdapter struct {
+	mu      sync.Mutex
+	allowed bool
+}
+
+func (a *switchAdapter) set(allowed bool) {
+	a.mu.Lock()
+	a.allowed = allowed
+	a.mu.Unlock()
+}
+
+func (a *switchAdapter) LoadPolicy() ([]Policy, []Grouping, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.allowed {
+		return []Policy{{Subject: "bob", Resource: "/api/articles/*", Action: "GET"}}, nil, nil
+	}
+	return []Policy{{Subject: "bob", Resource: "/api/articles/*", Action: "PUT"}}, nil, nil
+}
+
+// 重新加载撤销权限时, 并发请求用旧策略算出的决策不能写入缓存
+func TestRbac_ReloadConcurrent(t *testing.T) {
+	adapter := &switchAdapter{allowed: true}
+	r, err := New(adapter, 0)
+	if err != nil {
+		t.Fatalf("new rbac err: %s", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					r.Enforce("bob", "/api/articles/1", "GET")
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 500; i++ {
+		adapter.set(true)
+		if err := r.Reload(); err != nil {
+			t.Fatalf("reload err: %s", err)
+		}
+
+		adapter.set(false)
+		if err := r.Reload(); err != nil {
+			t.Fatalf("reload err: %s", err)
+		}
+
+		if r.Enforce("bob", "/api/articles/1", "GET") {
+			t.Errorf("bob should be denied after revoke, round %d", i)
+			break
+		}
+	}
+
+	close(stop)
+	wg.Wait()
+}
+
+// 模拟决策算出后, 写入缓存前发生了重新加载
+func TestRbac_StaleDecisionNotCached(t *testing.T) {
+	adapter := &switchAdapter{allowed: true}
+	r, err := New(adapter, 0)
+	if err != nil {
+		t.Fatalf("new rbac err: %s", err)
+	}
+
+	key := "bob\x00/api/articles/1\x00GET"
+
+	r.mu.RLock()
+	allowed := r.enforce("bob", "/api/articles/1", "GET")
+	generation := r.generation
+	r.mu.RUnlock()
+
+	adapter.set(false)
+	if err := r.Reload(); err != nil {
+		t.Fatalf("reload err: %s", err)
+	}
+
+	r.setCache(key, allowed, generation)
+
+	if r.Enforce("bob", "/api/articles/1", "GET") {
+		t.Error("stale decision should not be cached after reload")
+	}
+}
@@ -1,17 +1,26 @@
 package rds
 
 import (
+	"context"
 	"errors"
 	"log"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
 	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/coms/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
 )
 
 type Rds struct {
 	//	redis.Conn
 	*redis.Pool
+	name    string
+	showLog bool
+	ctx     context.Context
+	// 配置的读超时, ctx 的剩余时间更短时才覆盖
+	readTimeout time.Duration
 }
 
 // 返回 redis 的一个连接
@@ -27,14 +36,44 @@ func Ins(id ...string) *Rds {
 
 	v := yago.Component.Ins(name, func() interface{} {
 
-		val := initRedisConnPool(name)
+		val := &Rds{
+			Pool:    initRedisConnPool(name),
+			name:    name,
+			showLog: yago.Config.GetBool(name + ".show_log"),
+			// 与连接池的 read_timeout 一致, 单位为毫秒
+			readTimeout: time.Duration(yago.Config.GetInt64(name+".read_timeout")) * time.Millisecond,
+		}
+
+		// 连接池指标
+		if yago.MetricsEnabled() {
+			yago.RegisterMetrics(newPoolCollector(name, val.Pool))
+		}
+
 		return val
 	})
 
-	redisPool := v.(*redis.Pool)
+	return v.(*Rds)
+}
+
+// 实现 yago.HealthChecker
+func (r *Rds) HealthCheck(ctx context.Context) error {
+	rc, err := r.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = rc.Do("PING")
+	return err
+}
 
-	// rds := redisPool.Get()
-	return &Rds{Pool: redisPool}
+// 返回绑定了 ctx 的副本, 命令日志带上 ctx 中的 trace_id, span_id
+// ctx 有 deadline 时, 以剩余时间作为命令的读超时, 已经超时的不再执行
+// eg. rds.Ins().WithContext(ctx).Get("key")
+func (r *Rds) WithContext(ctx context.Context) *Rds {
+	c := *r
+	c.ctx = ctx
+	return &c
 }
 
 func (r *Rds) GetConn() redis.Conn {
@@ -42,6 +81,12 @@ func (r *Rds) GetConn() redis.Conn {
 }
 
 func (r *Rds) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
+	if r.ctx != nil {
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
 	rc := r.GetConn()
 	defer func(rc redis.Conn) {
 		err := rc.Close()
@@ -49,7 +94,86 @@ func (r *Rds) Do(commandName string, args ...interface{}) (reply interface{}, er
 			log.Println("[Redis] close redis conn err: ", err.Error())
 		}
 	}(rc)
-	return rc.Do(commandName, args...)
+
+	if !r.showLog {
+		return r.do(rc, commandName, args...)
+	}
+
+	begin := time.Now()
+
+	reply, err = r.do(rc, commandName, args...)
+
+	r.logCmd(commandName, args, time.Since(begin), err)
+
+	return reply, err
+}
+
+func (r *Rds) do(rc redis.Conn, commandName string, args ...interface{}) (reply interface{}, err error) {
+	if r.ctx == nil {
+		return rc.Do(commandName, args...)
+	}
+
+	deadline, ok := r.ctx.Deadline()
+	if !ok {
+		return rc.Do(commandName, args...)
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	if r.readTimeout > 0 && r.readTimeout <= timeout {
+		return rc.Do(commandName, args...)
+	}
+
+	return redis.DoWithTimeout(rc, timeout, commandName, args...)
+}
+
+func (r *Rds) logCmd(commandName string, args []interface{}, consume time.Duration, err error) {
+	entry := logger.Ins().Category("redis.cmd")
+	if r.ctx != nil {
+		entry = entry.WithContext(r.ctx)
+	}
+
+	entry = entry.WithFields(logrus.Fields{
+		"name":    r.name,
+		"cmd":     commandName,
+		"args":    args,
+		"consume": consume.Nanoseconds() / 1e6,
+	})
+
+	if err != nil && err != redis.ErrNil {
+		entry.WithField("hint", err.Error()).Error()
+	} else {
+		entry.Info()
+	}
+}
+
+// redis 连接池指标
+type poolCollector struct {
+	pool   *redis.Pool
+	active *prometheus.Desc
+	idle   *prometheus.Desc
+}
+
+func newPoolCollector(name string, pool *redis.Pool) *poolCollector {
+	labels := prometheus.Labels{"name": name}
+	return &poolCollector{
+		pool:   pool,
+		active: prometheus.NewDesc("yago_redis_pool_active_connections", "The number of connections in the pool, including idle ones.", nil, labels),
+		idle:   prometheus.NewDesc("yago_redis_pool_idle_connections", "The number of idle connections in the pool.", nil, labels),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.idle
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.Stats()
+	ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(stats.ActiveCount))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleCount))
 }
 
 func initRedisConnPool(name string) *redis.Pool {
@@ -0,0 +1,64 @@
+package yago
+
+import (
+	"sync"
+
+	"github.com/hulklab/yago/libs/semalib"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	concurrencyLimiters   = make(map[string]*semalib.Limiter)
+	concurrencyLimitersMu sync.Mutex
+)
+
+// 按配置段创建并发限制, 默认为 concurrency, 同一配置段的 http 路由组和 rpc 方法共享上限
+// eg. yago.NewHttpGroupRouter("/api", basehttp.NewConcurrencyLimit(yago.ConcurrencyLimiter()))
+func ConcurrencyLimiter(name ...string) *semalib.Limiter {
+	section := "concurrency"
+	if len(name) > 0 {
+		section = name[0]
+	}
+
+	concurrencyLimitersMu.Lock()
+	defer concurrencyLimitersMu.Unlock()
+
+	if l, ok := concurrencyLimiters[section]; ok {
+		return l
+	}
+
+	conf := semalib.LimiterConfig{
+		Limit:       Config.GetInt(section + ".limit"),
+		Adaptive:    Config.GetBool(section + ".adaptive"),
+		MinLimit:    Config.GetInt(section + ".min_limit"),
+		MaxLimit:    Config.GetInt(section + ".max_limit"),
+		MaxWait:     Config.GetDuration(section + ".max_wait"),
+		NormalRatio: Config.GetFloat64(section + ".normal_ratio"),
+		BatchRatio:  Config.GetFloat64(section + ".batch_ratio"),
+	}
+
+	l := semalib.NewLimiter(conf)
+	concurrencyLimiters[section] = l
+
+	if MetricsEnabled() {
+		labels := prometheus.Labels{"name": section}
+		RegisterMetrics(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   "yago",
+				Subsystem:   "concurrency",
+				Name:        "limit",
+				Help:        "Current concurrency limit.",
+				ConstLabels: labels,
+			}, func() float64 { return float64(l.Limit()) }),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   "yago",
+				Subsystem:   "concurrency",
+				Name:        "inflight",
+				Help:        "Number of in-flight requests under concurrency limit.",
+				ConstLabels: labels,
+			}, func() float64 { return float64(l.Inflight()) }),
+		)
+	}
+
+	return l
+}
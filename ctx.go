@@ -1,6 +1,7 @@
 package yago
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,9 +23,10 @@ const (
 )
 
 type ResponseBody struct {
-	ErrNo  int         `json:"errno"`
-	ErrMsg string      `json:"errmsg"`
-	Data   interface{} `json:"data,omitempty"`
+	ErrNo   int                    `json:"errno"`
+	ErrMsg  string                 `json:"errmsg"`
+	Data    interface{}            `json:"data,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
 }
 
 func newCtx(c *gin.Context) *Ctx {
@@ -77,19 +79,25 @@ func (c *Ctx) SetData(data interface{}) {
 	}
 	c.Set(ResponseKey, resp)
 
-	c.JSON(http.StatusOK, resp)
+	c.render(http.StatusOK, resp)
 }
 
-func (c *Ctx) setError(err Err) {
+func (c *Ctx) setError(err Err, details ...map[string]interface{}) {
+	err = LocalizeErr(c.GetLang(), err)
+
 	resp := &ResponseBody{
 		ErrNo:  err.Code(),
 		ErrMsg: err.Error(),
 		Data:   nil,
 	}
 
+	if len(details) > 0 {
+		resp.Details = details[0]
+	}
+
 	c.Set(ResponseKey, resp)
 
-	c.JSON(http.StatusOK, resp)
+	c.render(getHttpErrStatus(err.Code()), resp)
 }
 
 func (c *Ctx) SetError(err interface{}) {
@@ -105,7 +113,7 @@ func (c *Ctx) SetError(err interface{}) {
 		c.setError(v)
 	case validatorv10.ValidationErrors:
 		for _, fieldErr := range v {
-			e := ErrParam.String() + fieldErr.Translate(GetTranslator())
+			e := ErrParam.String() + LocalizeFieldErr(c.GetLang(), fieldErr)
 			c.setError(Err(e))
 			return
 		}
@@ -115,7 +123,9 @@ func (c *Ctx) SetError(err interface{}) {
 		var ye Err
 		e := errors.As(v, &ye)
 		if e {
-			c.setError(ye)
+			c.setError(ye, GetErrDetails(v))
+		} else if c.deadlineExceeded(v) {
+			c.setError(ErrTimeout)
 		} else {
 			c.setError(NewErr(v.Error()))
 		}
@@ -171,6 +181,27 @@ func (c *Ctx) GetResponse() (*ResponseBody, bool) {
 	}
 }
 
+// 使用指定的 http status 渲染响应, 如回放保存的响应
+func (c *Ctx) SetResponse(status int, resp *ResponseBody) {
+	c.Set(ResponseKey, resp)
+
+	c.render(status, resp)
+}
+
+// 请求的 context, 带有 trace 和路由超时的 deadline, 传给 service, orm, rds 和 third
+// eg. rds.Ins().WithContext(c.GetContext()).Get("key")
+func (c *Ctx) GetContext() context.Context {
+	return c.Request.Context()
+}
+
+// 请求超出 deadline 后, 下游返回的错误都按超时处理
+func (c *Ctx) deadlineExceeded(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return c.Request != nil && c.Request.Context().Err() == context.DeadlineExceeded
+}
+
 func (c *Ctx) Copy() *Ctx {
 	c.Context = c.Context.Copy()
 	return c
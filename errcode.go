@@ -0,0 +1,355 @@
+package yago
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+)
+
+// 错误码登记信息, 用于检查重复的错误码, 映射 http status 和 grpc code, 以及导出文档
+// http status 和 grpc code 的映射在 Run 时从登记信息生成
+type ErrCode struct {
+	Code    int
+	Message string
+	Module  string
+	// 为 0 时不映射
+	HttpStatus int
+	// 为 codes.OK 时不映射
+	GrpcCode    codes.Code
+	Description string
+}
+
+func (ec *ErrCode) Err() Err {
+	return NewErr(ec.Code, ec.Message)
+}
+
+type ErrCodeOption func(ec *ErrCode)
+
+func WithErrHttpStatus(status int) ErrCodeOption {
+	return func(ec *ErrCode) {
+		ec.HttpStatus = status
+	}
+}
+
+func WithErrGrpcCode(code codes.Code) ErrCodeOption {
+	return func(ec *ErrCode) {
+		ec.GrpcCode = code
+	}
+}
+
+func WithErrDescription(desc string) ErrCodeOption {
+	return func(ec *ErrCode) {
+		ec.Description = desc
+	}
+}
+
+var (
+	errCodeMu       sync.RWMutex
+	errCodeRegistry = make(map[int]*ErrCode)
+)
+
+// 登记错误码, 通常在包变量中声明, 错误码重复时 panic
+// eg. var ErrUserNotFound = yago.RegisterErr("user", 1001, "user not found", yago.WithErrHttpStatus(http.StatusNotFound), yago.WithErrGrpcCode(codes.NotFound))
+func RegisterErr(module string, code int, msg string, opts ...ErrCodeOption) Err {
+	ec := &ErrCode{
+		Code:    code,
+		Message: msg,
+		Module:  module,
+	}
+
+	for _, opt := range opts {
+		opt(ec)
+	}
+
+	registerErrCode(ec)
+
+	return ec.Err()
+}
+
+func registerErrCode(ec *ErrCode) {
+	if ec.Code == ok.Code() {
+		log.Panicf("err code %d is reserved for ok", ec.Code)
+	}
+
+	errCodeMu.Lock()
+	defer errCodeMu.Unlock()
+
+	if exist, ok := errCodeRegistry[ec.Code]; ok {
+		log.Panicf("err code duplicate : %d, registered by module %s, conflict with module %s", ec.Code, exist.Module, ec.Module)
+	}
+
+	errCodeRegistry[ec.Code] = ec
+}
+
+func GetErrCode(code int) (*ErrCode, bool) {
+	errCodeMu.RLock()
+	defer errCodeMu.RUnlock()
+
+	ec, ok := errCodeRegistry[code]
+	return ec, ok
+}
+
+// 按错误码排序
+func GetErrCodes() []*ErrCode {
+	errCodeMu.RLock()
+	defer errCodeMu.RUnlock()
+
+	list := make([]*ErrCode, 0, len(errCodeRegistry))
+	for _, ec := range errCodeRegistry {
+		list = append(list, ec)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+
+	return list
+}
+
+// 带详情和原因的错误, 详情会返回给调用方, 原因只用于日志
+// eg. return yago.ErrParam.WithDetails(map[string]interface{}{"field": "name"})
+// eg. return g.ErrOrderNotFound.WithCause(err)
+type DetailedErr struct {
+	ye      Err
+	details map[string]interface{}
+	cause   error
+}
+
+func NewDetailedErr(ye Err, details map[string]interface{}, cause error) *DetailedErr {
+	return &DetailedErr{
+		ye:      ye,
+		details: details,
+		cause:   cause,
+	}
+}
+
+func (e Err) WithDetails(details map[string]interface{}) *DetailedErr {
+	return NewDetailedErr(e, details, nil)
+}
+
+func (e Err) WithCause(cause error) *DetailedErr {
+	return NewDetailedErr(e, nil, cause)
+}
+
+// 合并详情, 返回新的错误
+func (e *DetailedErr) WithDetails(details map[string]interface{}) *DetailedErr {
+	merged := make(map[string]interface{}, len(e.details)+len(details))
+	for k, v := range e.details {
+		merged[k] = v
+	}
+	for k, v := range details {
+		merged[k] = v
+	}
+
+	return NewDetailedErr(e.ye, merged, e.cause)
+}
+
+func (e *DetailedErr) WithCause(cause error) *DetailedErr {
+	return NewDetailedErr(e.ye, e.details, cause)
+}
+
+func (e *DetailedErr) Code() int {
+	return e.ye.Code()
+}
+
+func (e *DetailedErr) Error() string {
+	if e.cause == nil {
+		return e.ye.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.ye.Error(), e.cause)
+}
+
+// errors.As 可以取出 yago.Err
+func (e *DetailedErr) Unwrap() error {
+	return e.ye
+}
+
+// errors.Is 可以匹配原因
+func (e *DetailedErr) Is(target error) bool {
+	return e.cause != nil && errors.Is(e.cause, target)
+}
+
+func (e *DetailedErr) Cause() error {
+	return e.cause
+}
+
+func (e *DetailedErr) Details() map[string]interface{} {
+	return e.details
+}
+
+type errDetailer interface {
+	Details() map[string]interface{}
+}
+
+// 取出错误链上的详情, 没有时返回 nil
+func GetErrDetails(err error) map[string]interface{} {
+	var d errDetailer
+	if errors.As(err, &d) {
+		return d.Details()
+	}
+	return nil
+}
+
+type builtinErrCode struct {
+	ye *Err
+	// 错误变量的默认值, 与 *ye 不同时说明应用替换了错误变量
+	def        Err
+	httpStatus int
+	grpcCode   codes.Code
+	desc       string
+}
+
+// 内置错误码, 错误变量可以被应用替换, 所以保存变量地址, 在 LoadErrCodes 时按替换后的值登记
+var builtinErrCodes = []builtinErrCode{
+	{&E, E, 0, codes.OK, "通用的自定义错误"},
+	{&ErrParam, ErrParam, http.StatusBadRequest, codes.InvalidArgument, "参数错误"},
+	{&ErrSign, ErrSign, http.StatusUnauthorized, codes.Unauthenticated, "签名校验失败"},
+	{&ErrAuth, ErrAuth, http.StatusUnauthorized, codes.Unauthenticated, "认证失败"},
+	{&ErrForbidden, ErrForbidden, http.StatusForbidden, codes.PermissionDenied, "没有权限"},
+	{&ErrNotLogin, ErrNotLogin, http.StatusUnauthorized, codes.Unauthenticated, "用户未登录"},
+	{&ErrSystem, ErrSystem, http.StatusInternalServerError, codes.Internal, "系统错误, 详细原因记录在日志中"},
+	{&ErrOperate, ErrOperate, http.StatusBadRequest, codes.FailedPrecondition, "操作失败"},
+	{&ErrUnknown, ErrUnknown, http.StatusInternalServerError, codes.Unknown, "未知错误"},
+	{&ErrTooManyRequests, ErrTooManyRequests, http.StatusTooManyRequests, codes.ResourceExhausted, "请求过于频繁, 被限流"},
+	{&ErrOverloaded, ErrOverloaded, http.StatusServiceUnavailable, codes.Unavailable, "服务过载, 并发超出限制"},
+	{&ErrConflict, ErrConflict, http.StatusConflict, codes.Aborted, "请求冲突, 如相同的 Idempotency-Key 正在处理"},
+	{&ErrTimeout, ErrTimeout, http.StatusGatewayTimeout, codes.DeadlineExceeded, "请求超时, 超出了路由或 X-Request-Timeout 的时间"},
+}
+
+var loadErrCodesOnce sync.Once
+
+// 登记内置错误码, Run 时在 init hook 之前调用, 不调用 Run 的测试需要手动调用
+// 应用替换的内置错误变量已经用 RegisterErr 登记, 或以 yago 模块覆盖登记了内置错误码时, 以应用登记的为准
+// 其他模块登记了内置错误码时 panic
+func LoadErrCodes() {
+	loadErrCodesOnce.Do(func() {
+		for _, b := range builtinErrCodes {
+			if exist, ok := GetErrCode(b.ye.Code()); ok {
+				if exist.Message == b.ye.Error() && (exist.Module == "yago" || *b.ye != b.def) {
+					continue
+				}
+				log.Panicf("err code duplicate : %d, registered by module %s, conflict with module yago", exist.Code, exist.Module)
+			}
+
+			registerErrCode(&ErrCode{
+				Code:        b.ye.Code(),
+				Message:     b.ye.Error(),
+				Module:      "yago",
+				HttpStatus:  b.httpStatus,
+				GrpcCode:    b.grpcCode,
+				Description: b.desc,
+			})
+		}
+	})
+}
+
+func init() {
+	// ./app errcode -f json -o errcode.json
+	AddCmdRouter("errcode", "Export error code catalog", errCodeCmdAction, CmdStringArg{
+		Name: "format", Shorthand: "f", Value: "markdown", Usage: "markdown or json",
+	}, CmdStringArg{
+		Name: "output", Shorthand: "o", Value: "", Usage: "output file, default stdout",
+	})
+}
+
+func errCodeCmdAction(cmd *cobra.Command, args []string) {
+	LoadErrCodes()
+
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fatalln("create output file err:", err.Error())
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	switch format {
+	case "markdown", "md":
+		err = ExportErrCodesMarkdown(w)
+	case "json":
+		err = ExportErrCodesJSON(w)
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+
+	if err != nil {
+		fatalln("export err code err:", err.Error())
+	}
+}
+
+type errCodeDoc struct {
+	Code        int    `json:"code"`
+	Message     string `json:"message"`
+	Module      string `json:"module"`
+	HttpStatus  int    `json:"http_status,omitempty"`
+	GrpcCode    string `json:"grpc_code,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func getErrCodeDocs() []errCodeDoc {
+	list := GetErrCodes()
+	docs := make([]errCodeDoc, 0, len(list))
+
+	for _, ec := range list {
+		doc := errCodeDoc{
+			Code:        ec.Code,
+			Message:     ec.Message,
+			Module:      ec.Module,
+			HttpStatus:  ec.HttpStatus,
+			Description: ec.Description,
+		}
+		if ec.GrpcCode != codes.OK {
+			doc.GrpcCode = ec.GrpcCode.String()
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
+
+func ExportErrCodesJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(getErrCodeDocs())
+}
+
+func ExportErrCodesMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ").Replace
+
+	var b strings.Builder
+	b.WriteString("# Error Codes\n\n")
+	b.WriteString("| Code | Module | Message | HTTP Status | gRPC Code | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+
+	for _, doc := range getErrCodeDocs() {
+		status := ""
+		if doc.HttpStatus != 0 {
+			status = strconv.Itoa(doc.HttpStatus)
+		}
+
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s |\n",
+			doc.Code, escape(doc.Module), escape(doc.Message), status, doc.GrpcCode, escape(doc.Description))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,148 @@
+package yago
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// go test -v . -test.run "TestLoadErrCodes|TestRegisterErr|TestDetailedErr"
+
+// 替换内置错误变量后重新登记, 结束时恢复
+func reloadErrCodesTest(t *testing.T, replace func()) {
+	registry, param := errCodeRegistry, ErrParam
+	renderers, offers, on, table := httpRenderers, httpRenderOffers, httpErrStatusOn, httpErrStatusTable
+	t.Cleanup(func() {
+		errCodeRegistry, ErrParam = registry, param
+		httpRenderers, httpRenderOffers, httpErrStatusOn, httpErrStatusTable = renderers, offers, on, table
+		loadErrCodesOnce = sync.Once{}
+	})
+
+	errCodeRegistry = make(map[int]*ErrCode)
+	loadErrCodesOnce = sync.Once{}
+	replace()
+	LoadErrCodes()
+}
+
+func TestLoadErrCodes(t *testing.T) {
+	var errNotFound Err
+	reloadErrCodesTest(t, func() {
+		ErrParam = Err("4000=Param err")
+		errNotFound = RegisterErr("test", 4004, "not found", WithErrHttpStatus(http.StatusNotFound))
+	})
+
+	if ec, ok := GetErrCode(4000); !ok || ec.HttpStatus != http.StatusBadRequest || ec.Message != "Param err" {
+		t.Errorf("replaced ErrParam got %+v", ec)
+	}
+	if _, ok := GetErrCode(2); ok {
+		t.Error("replaced builtin code should not be registered")
+	}
+
+	app := &App{HttpErrStatusOn: true, HttpErrStatus: map[int]int{ErrConflict.Code(): http.StatusBadRequest}}
+	app.loadHttpRender()
+
+	cases := []struct {
+		name   string
+		code   int
+		status int
+	}{
+		{"replaced builtin", ErrParam.Code(), http.StatusBadRequest},
+		{"original builtin code", 2, http.StatusOK},
+		{"builtin", ErrSystem.Code(), http.StatusInternalServerError},
+		{"overridden", ErrConflict.Code(), http.StatusBadRequest},
+		{"registered", errNotFound.Code(), http.StatusNotFound},
+		{"custom", E.Code(), http.StatusOK},
+		{"unregistered", 99999, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		if got := getHttpErrStatus(c.code); got != c.status {
+			t.Errorf("%s %d got status %d, want %d", c.name, c.code, got, c.status)
+		}
+	}
+
+	app.HttpErrStatusOn = false
+	app.loadHttpRender()
+	if got := getHttpErrStatus(ErrSystem.Code()); got != http.StatusOK {
+		t.Errorf("status with http_err_status_on off got %d", got)
+	}
+}
+
+func errCodePanics(f func()) (p bool) {
+	defer func() {
+		p = recover() != nil
+	}()
+	f()
+	return false
+}
+
+// 其他模块在 Run 之前登记了内置错误码时 panic
+func TestLoadErrCodes_Duplicate(t *testing.T) {
+	if !errCodePanics(func() {
+		reloadErrCodesTest(t, func() {
+			RegisterErr("order", ErrSign.Code(), "order not found")
+		})
+	}) {
+		t.Error("builtin code registered by other module should panic")
+	}
+
+	// yago 模块以相同信息覆盖登记, 使用应用登记的 http status
+	reloadErrCodesTest(t, func() {
+		RegisterErr("yago", ErrSign.Code(), ErrSign.Error(), WithErrHttpStatus(http.StatusForbidden))
+	})
+	if ec, ok := GetErrCode(ErrSign.Code()); !ok || ec.HttpStatus != http.StatusForbidden {
+		t.Errorf("overridden builtin got %+v", ec)
+	}
+}
+
+func TestRegisterErr(t *testing.T) {
+	reloadErrCodesTest(t, func() {})
+
+	RegisterErr("test", 4100, "a|b", WithErrDescription("line1\nline2"))
+
+	if errCodePanics(func() { RegisterErr("dup", 4101, "dup") }) {
+		t.Error("new code should not panic")
+	}
+	if !errCodePanics(func() { RegisterErr("dup", 4100, "dup") }) {
+		t.Error("duplicate code should panic")
+	}
+	if !errCodePanics(func() { RegisterErr("dup", ErrParam.Code(), "dup") }) {
+		t.Error("duplicate builtin code should panic")
+	}
+	if !errCodePanics(func() { RegisterErr("dup", 0, "dup") }) {
+		t.Error("code reserved for ok should panic")
+	}
+
+	var b strings.Builder
+	if err := ExportErrCodesMarkdown(&b); err != nil {
+		t.Fatal("export err:", err)
+	}
+	if !strings.Contains(b.String(), `| 4100 | test | a\|b |  |  | line1 line2 |`) {
+		t.Errorf("markdown should escape | and newline, got %s", b.String())
+	}
+}
+
+func TestDetailedErr(t *testing.T) {
+	cause := errors.New("db down")
+	err := fmt.Errorf("wrap: %w", ErrForbidden.WithDetails(map[string]interface{}{"a": 1}).WithCause(cause).WithDetails(map[string]interface{}{"b": 2}))
+
+	var ye Err
+	if !errors.As(err, &ye) || ye.Code() != ErrForbidden.Code() {
+		t.Errorf("errors.As got %v", ye)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is should match cause")
+	}
+	if d := GetErrDetails(err); len(d) != 2 || d["a"] != 1 || d["b"] != 2 {
+		t.Errorf("details got %v", d)
+	}
+	if got := err.Error(); got != "wrap: Forbidden: db down" {
+		t.Errorf("error message got %s", got)
+	}
+	if GetErrDetails(cause) != nil {
+		t.Error("plain error should not have details")
+	}
+}
@@ -22,6 +22,14 @@ var (
 	ErrSystem    = Err("7=System error")
 	ErrOperate   = Err("8=")
 	ErrUnknown   = Err("9=Unknown error")
+	// 请求被限流
+	ErrTooManyRequests = Err("10=Too many requests")
+	// 并发超出限制, 快速失败
+	ErrOverloaded = Err("11=Server overloaded")
+	// 请求与正在处理的请求冲突, 如相同的 Idempotency-Key
+	ErrConflict = Err("12=Request conflict")
+	// 请求超出了 deadline
+	ErrTimeout = Err("13=Request timeout")
 )
 
 func (e Err) Error() string {
@@ -106,6 +114,7 @@ func NewErr(err interface{}, args ...interface{}) Err {
 }
 
 // 返回业务报错（业务报错给接口展示），包裹系统错误（系统错误转到日志记录）
+// errors.As 可以取出 yago.Err, errors.Is 可以匹配被包裹的系统错误
 func WrapErr(ye Err, err error) error {
 	if err == nil {
 		return NewErr("err can not be nil when use yago.WrapErr()")
@@ -113,7 +122,7 @@ func WrapErr(ye Err, err error) error {
 	if ye == ok {
 		return NewErr("ye can not be OK when use yago.WrapErr()")
 	}
-	return fmt.Errorf("%w: %s", ye, err)
+	return NewDetailedErr(ye, nil, err)
 }
 
 type HTTPCodeError int
@@ -1,9 +1,12 @@
 package ghttp
 
 import (
+	"time"
+
 	"github.com/hulklab/yago"
 	"github.com/hulklab/yago/base/basehttp"
 	"github.com/hulklab/yago/example/app/libs/trace"
+	"github.com/hulklab/yago/libs/semalib"
 )
 
 type BaseHttp struct {
@@ -15,9 +18,32 @@ func (h *BaseHttp) GetTraceCtx(c *yago.Ctx) *trace.Context {
 	return ctx
 }
 
-// 全局 routeGroup
-var Root = yago.NewHttpGroupRouter("/", BizLog)
+// 全局 routeGroup, 访问日志由 app.http_access_log_on 开启
+var Root = yago.NewHttpGroupRouter("/")
 
 type HttpMetadata struct {
 	Label string `json:"label"`
+	// 不记录访问日志
+	NoAccessLog bool `json:"no_access_log"`
+	// 并发限制中的优先级
+	Priority semalib.Priority `json:"priority"`
+	// 路由的超时时间, 为 0 时使用路由组或 http_timeout.default
+	Timeout time.Duration `json:"timeout"`
+}
+
+func (m HttpMetadata) DisableAccessLog() bool {
+	return m.NoAccessLog
+}
+
+func (m HttpMetadata) ConcurrencyPriority() semalib.Priority {
+	return m.Priority
+}
+
+func (m HttpMetadata) RequestTimeout() time.Duration {
+	return m.Timeout
+}
+
+// openapi 文档中的接口名称
+func (m HttpMetadata) OpenAPISummary() string {
+	return m.Label
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/hulklab/yago/coms/logger"
 	"github.com/hulklab/yago/example/app/g"
+	"github.com/hulklab/yago/libs/tracelib"
 	"github.com/sirupsen/logrus"
 )
 
@@ -46,8 +47,12 @@ func (t *Context) GetString(key string) string {
 	return ""
 }
 
+// 优先使用自定义的 trace id, 其次是 traceparent 传递过来的
 func (t *Context) GetTraceId() string {
-	return t.GetString(traceIdKey)
+	if traceId := t.GetString(traceIdKey); traceId != "" {
+		return traceId
+	}
+	return tracelib.TraceIDFromContext(t.Context)
 }
 
 func (t *Context) SetTraceId(traceId string) {
@@ -59,5 +64,5 @@ func (t *Context) Logger() *logrus.Entry {
 		"trace_id": t.GetTraceId(),
 	}
 
-	return logger.Ins().WithFields(field)
+	return logger.Ins().WithContext(t.Context).WithFields(field)
 }
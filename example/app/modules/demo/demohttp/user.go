@@ -20,7 +20,7 @@ func init() {
 	ghttp.Root.Post("/demo/user/add", h.AddAction)
 	ghttp.Root.Post("/demo/user/delete", h.DeleteAction)
 	ghttp.Root.Post("/demo/user/update", h.UpdateAction)
-	ghttp.Root.Post("/demo/user/list", h.ListAction)
+	ghttp.Root.PostTyped("/demo/user/list", h.ListAction)
 	ghttp.Root.Get("/demo/user/detail", h.DetailAction)
 
 	// routing groups are recommended
@@ -37,19 +37,10 @@ func init() {
 
 }
 
-// curl 'http://127.0.0.1:8080/user/list' -H "Content-type:application/json" -XPOST -d '{"page_size":10,"page_num":1}'
-func (h *UserHttp) ListAction(c *yago.Ctx) {
-	req := &demodto.UserListReq{}
-
-	ctx := h.GetTraceCtx(c)
-
-	if err := c.ShouldBind(req); err != nil {
-		c.SetError(err)
-		return
-	}
-
-	data, err := demoservice.NewUserService(ctx).GetList(req)
-	c.SetDataOrErr(data, err)
+// typed handler, 通过 PostTyped 注册, req 自动绑定和校验, 返回值自动渲染
+// curl 'http://127.0.0.1:8080/demo/user/list' -H "Content-type:application/json" -XPOST -d '{"page_size":10,"page_num":1}'
+func (h *UserHttp) ListAction(c *yago.Ctx, req *demodto.UserListReq) (*demodto.UserListResp, error) {
+	return demoservice.NewUserService(h.GetTraceCtx(c)).GetList(req)
 }
 
 // curl 'http://127.0.0.1:8080/demo/user/add' -H "Content-type:application/x-www-form-urlencoded" -XPOST -d "username=lisi&phone=13090001112"
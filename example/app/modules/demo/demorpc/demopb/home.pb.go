@@ -1,294 +1,283 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        (unknown)
 // source: home.proto
 
 package app_demopb
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-
 import (
-	context "golang.org/x/net/context"
-	grpc "google.golang.org/grpc"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
 )
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ = proto.Marshal
-var _ = fmt.Errorf
-var _ = math.Inf
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the proto package it is being compiled against.
-// A compilation error at this line likely means your copy of the
-// proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
 
 type HelloRequest struct {
-	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
-}
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-func (m *HelloRequest) Reset()         { *m = HelloRequest{} }
-func (m *HelloRequest) String() string { return proto.CompactTextString(m) }
-func (*HelloRequest) ProtoMessage()    {}
-func (*HelloRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_home_992161aabd05aee7, []int{0}
-}
-func (m *HelloRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_HelloRequest.Unmarshal(m, b)
-}
-func (m *HelloRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_HelloRequest.Marshal(b, m, deterministic)
-}
-func (dst *HelloRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_HelloRequest.Merge(dst, src)
-}
-func (m *HelloRequest) XXX_Size() int {
-	return xxx_messageInfo_HelloRequest.Size(m)
-}
-func (m *HelloRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_HelloRequest.DiscardUnknown(m)
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 }
 
-var xxx_messageInfo_HelloRequest proto.InternalMessageInfo
-
-func (m *HelloRequest) GetName() string {
-	if m != nil {
-		return m.Name
+func (x *HelloRequest) Reset() {
+	*x = HelloRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_home_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	return ""
 }
 
-type HelloReply struct {
-	Data                 string   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
-}
-
-func (m *HelloReply) Reset()         { *m = HelloReply{} }
-func (m *HelloReply) String() string { return proto.CompactTextString(m) }
-func (*HelloReply) ProtoMessage()    {}
-func (*HelloReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_home_992161aabd05aee7, []int{1}
-}
-func (m *HelloReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_HelloReply.Unmarshal(m, b)
-}
-func (m *HelloReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_HelloReply.Marshal(b, m, deterministic)
-}
-func (dst *HelloReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_HelloReply.Merge(dst, src)
-}
-func (m *HelloReply) XXX_Size() int {
-	return xxx_messageInfo_HelloReply.Size(m)
-}
-func (m *HelloReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_HelloReply.DiscardUnknown(m)
+func (x *HelloRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var xxx_messageInfo_HelloReply proto.InternalMessageInfo
+func (*HelloRequest) ProtoMessage() {}
 
-func (m *HelloReply) GetData() string {
-	if m != nil {
-		return m.Data
+func (x *HelloRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_home_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
-}
-
-type HelloStreamReply struct {
-	Data                 string   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	return mi.MessageOf(x)
 }
 
-func (m *HelloStreamReply) Reset()         { *m = HelloStreamReply{} }
-func (m *HelloStreamReply) String() string { return proto.CompactTextString(m) }
-func (*HelloStreamReply) ProtoMessage()    {}
-func (*HelloStreamReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_home_992161aabd05aee7, []int{2}
-}
-func (m *HelloStreamReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_HelloStreamReply.Unmarshal(m, b)
-}
-func (m *HelloStreamReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_HelloStreamReply.Marshal(b, m, deterministic)
-}
-func (dst *HelloStreamReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_HelloStreamReply.Merge(dst, src)
-}
-func (m *HelloStreamReply) XXX_Size() int {
-	return xxx_messageInfo_HelloStreamReply.Size(m)
-}
-func (m *HelloStreamReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_HelloStreamReply.DiscardUnknown(m)
+// Deprecated: Use HelloRequest.ProtoReflect.Descriptor instead.
+func (*HelloRequest) Descriptor() ([]byte, []int) {
+	return file_home_proto_rawDescGZIP(), []int{0}
 }
 
-var xxx_messageInfo_HelloStreamReply proto.InternalMessageInfo
-
-func (m *HelloStreamReply) GetData() string {
-	if m != nil {
-		return m.Data
+func (x *HelloRequest) GetName() string {
+	if x != nil {
+		return x.Name
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*HelloRequest)(nil), "app.demopb.HelloRequest")
-	proto.RegisterType((*HelloReply)(nil), "app.demopb.HelloReply")
-	proto.RegisterType((*HelloStreamReply)(nil), "app.demopb.HelloStreamReply")
-}
-
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+type HelloReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-// HomeClient is the client API for Home service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type HomeClient interface {
-	Hello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error)
-	HelloStream(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (Home_HelloStreamClient, error)
+	Data string `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
 }
 
-type homeClient struct {
-	cc *grpc.ClientConn
+func (x *HelloReply) Reset() {
+	*x = HelloReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_home_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-func NewHomeClient(cc *grpc.ClientConn) HomeClient {
-	return &homeClient{cc}
+func (x *HelloReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (c *homeClient) Hello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error) {
-	out := new(HelloReply)
-	err := c.cc.Invoke(ctx, "/app.demopb.Home/Hello", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
+func (*HelloReply) ProtoMessage() {}
 
-func (c *homeClient) HelloStream(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (Home_HelloStreamClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Home_serviceDesc.Streams[0], "/app.demopb.Home/HelloStream", opts...)
-	if err != nil {
-		return nil, err
+func (x *HelloReply) ProtoReflect() protoreflect.Message {
+	mi := &file_home_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	x := &homeHelloStreamClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	return x, nil
-}
-
-type Home_HelloStreamClient interface {
-	Recv() (*HelloStreamReply, error)
-	grpc.ClientStream
+	return mi.MessageOf(x)
 }
 
-type homeHelloStreamClient struct {
-	grpc.ClientStream
+// Deprecated: Use HelloReply.ProtoReflect.Descriptor instead.
+func (*HelloReply) Descriptor() ([]byte, []int) {
+	return file_home_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *homeHelloStreamClient) Recv() (*HelloStreamReply, error) {
-	m := new(HelloStreamReply)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (x *HelloReply) GetData() string {
+	if x != nil {
+		return x.Data
 	}
-	return m, nil
+	return ""
 }
 
-// HomeServer is the server API for Home service.
-type HomeServer interface {
-	Hello(context.Context, *HelloRequest) (*HelloReply, error)
-	HelloStream(*HelloRequest, Home_HelloStreamServer) error
-}
+type HelloStreamReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-func RegisterHomeServer(s *grpc.Server, srv HomeServer) {
-	s.RegisterService(&_Home_serviceDesc, srv)
+	Data string `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
 }
 
-func _Home_Hello_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(HelloRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (x *HelloStreamReply) Reset() {
+	*x = HelloStreamReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_home_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	if interceptor == nil {
-		return srv.(HomeServer).Hello(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/app.demopb.Home/Hello",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(HomeServer).Hello(ctx, req.(*HelloRequest))
-	}
-	return interceptor(ctx, in, info, handler)
 }
 
-func _Home_HelloStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(HelloRequest)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
-	}
-	return srv.(HomeServer).HelloStream(m, &homeHelloStreamServer{stream})
+func (x *HelloStreamReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-type Home_HelloStreamServer interface {
-	Send(*HelloStreamReply) error
-	grpc.ServerStream
-}
+func (*HelloStreamReply) ProtoMessage() {}
 
-type homeHelloStreamServer struct {
-	grpc.ServerStream
+func (x *HelloStreamReply) ProtoReflect() protoreflect.Message {
+	mi := &file_home_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (x *homeHelloStreamServer) Send(m *HelloStreamReply) error {
-	return x.ServerStream.SendMsg(m)
+// Deprecated: Use HelloStreamReply.ProtoReflect.Descriptor instead.
+func (*HelloStreamReply) Descriptor() ([]byte, []int) {
+	return file_home_proto_rawDescGZIP(), []int{2}
 }
 
-var _Home_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "app.demopb.Home",
-	HandlerType: (*HomeServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Hello",
-			Handler:    _Home_Hello_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "HelloStream",
-			Handler:       _Home_HelloStream_Handler,
-			ServerStreams: true,
-		},
-	},
-	Metadata: "home.proto",
+func (x *HelloStreamReply) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
 }
 
-func init() { proto.RegisterFile("home.proto", fileDescriptor_home_992161aabd05aee7) }
-
-var fileDescriptor_home_992161aabd05aee7 = []byte{
-	// 170 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xca, 0xc8, 0xcf, 0x4d,
-	0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x4a, 0x2c, 0x28, 0xd0, 0x4b, 0x49, 0xcd, 0xcd,
-	0x2f, 0x48, 0x52, 0x52, 0xe2, 0xe2, 0xf1, 0x48, 0xcd, 0xc9, 0xc9, 0x0f, 0x4a, 0x2d, 0x2c, 0x4d,
-	0x2d, 0x2e, 0x11, 0x12, 0xe2, 0x62, 0xc9, 0x4b, 0xcc, 0x4d, 0x95, 0x60, 0x54, 0x60, 0xd4, 0xe0,
-	0x0c, 0x02, 0xb3, 0x95, 0x14, 0xb8, 0xb8, 0xa0, 0x6a, 0x0a, 0x72, 0x2a, 0x41, 0x2a, 0x52, 0x12,
-	0x4b, 0x12, 0x61, 0x2a, 0x40, 0x6c, 0x25, 0x35, 0x2e, 0x01, 0xb0, 0x8a, 0xe0, 0x92, 0xa2, 0xd4,
-	0xc4, 0x5c, 0x9c, 0xea, 0x8c, 0xfa, 0x18, 0xb9, 0x58, 0x3c, 0xf2, 0x73, 0x53, 0x85, 0xac, 0xb9,
-	0x58, 0xc1, 0x1a, 0x84, 0x24, 0xf4, 0x10, 0x8e, 0xd1, 0x43, 0x76, 0x89, 0x94, 0x18, 0x16, 0x99,
-	0x82, 0x9c, 0x4a, 0x25, 0x06, 0x21, 0x4f, 0x2e, 0x6e, 0x24, 0xdb, 0xf0, 0x18, 0x21, 0x83, 0x21,
-	0x83, 0xe4, 0x40, 0x25, 0x06, 0x03, 0xc6, 0x24, 0x36, 0x70, 0x88, 0x18, 0x03, 0x02, 0x00, 0x00,
-	0xff, 0xff, 0xa0, 0x33, 0x9a, 0xf8, 0x1f, 0x01, 0x00, 0x00,
+var File_home_proto protoreflect.FileDescriptor
+
+var file_home_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x68, 0x6f, 0x6d, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x61, 0x70,
+	0x70, 0x2e, 0x64, 0x65, 0x6d, 0x6f, 0x70, 0x62, 0x22, 0x22, 0x0a, 0x0c, 0x48, 0x65, 0x6c, 0x6c,
+	0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x20, 0x0a, 0x0a,
+	0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x26,
+	0x0a, 0x10, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0x8e, 0x01, 0x0a, 0x04, 0x48, 0x6f, 0x6d, 0x65, 0x12,
+	0x3b, 0x0a, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x12, 0x18, 0x2e, 0x61, 0x70, 0x70, 0x2e, 0x64,
+	0x65, 0x6d, 0x6f, 0x70, 0x62, 0x2e, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x16, 0x2e, 0x61, 0x70, 0x70, 0x2e, 0x64, 0x65, 0x6d, 0x6f, 0x70, 0x62, 0x2e,
+	0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x49, 0x0a, 0x0b,
+	0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x18, 0x2e, 0x61, 0x70,
+	0x70, 0x2e, 0x64, 0x65, 0x6d, 0x6f, 0x70, 0x62, 0x2e, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x61, 0x70, 0x70, 0x2e, 0x64, 0x65, 0x6d, 0x6f,
+	0x70, 0x62, 0x2e, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x22, 0x00, 0x30, 0x01, 0x42, 0x4c, 0x5a, 0x4a, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x68, 0x75, 0x6c, 0x6b, 0x6c, 0x61, 0x62, 0x2f, 0x79, 0x61,
+	0x67, 0x6f, 0x2f, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2f, 0x61, 0x70, 0x70, 0x2f, 0x6d,
+	0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x2f, 0x64, 0x65, 0x6d, 0x6f, 0x2f, 0x64, 0x65, 0x6d, 0x6f,
+	0x72, 0x70, 0x63, 0x2f, 0x64, 0x65, 0x6d, 0x6f, 0x70, 0x62, 0x3b, 0x61, 0x70, 0x70, 0x5f, 0x64,
+	0x65, 0x6d, 0x6f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_home_proto_rawDescOnce sync.Once
+	file_home_proto_rawDescData = file_home_proto_rawDesc
+)
+
+func file_home_proto_rawDescGZIP() []byte {
+	file_home_proto_rawDescOnce.Do(func() {
+		file_home_proto_rawDescData = protoimpl.X.CompressGZIP(file_home_proto_rawDescData)
+	})
+	return file_home_proto_rawDescData
+}
+
+var file_home_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_home_proto_goTypes = []interface{}{
+	(*HelloRequest)(nil),     // 0: app.demopb.HelloRequest
+	(*HelloReply)(nil),       // 1: app.demopb.HelloReply
+	(*HelloStreamReply)(nil), // 2: app.demopb.HelloStreamReply
+}
+var file_home_proto_depIdxs = []int32{
+	0, // 0: app.demopb.Home.Hello:input_type -> app.demopb.HelloRequest
+	0, // 1: app.demopb.Home.HelloStream:input_type -> app.demopb.HelloRequest
+	1, // 2: app.demopb.Home.Hello:output_type -> app.demopb.HelloReply
+	2, // 3: app.demopb.Home.HelloStream:output_type -> app.demopb.HelloStreamReply
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_home_proto_init() }
+func file_home_proto_init() {
+	if File_home_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_home_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelloRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_home_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelloReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_home_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HelloStreamReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_home_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_home_proto_goTypes,
+		DependencyIndexes: file_home_proto_depIdxs,
+		MessageInfos:      file_home_proto_msgTypes,
+	}.Build()
+	File_home_proto = out.File
+	file_home_proto_rawDesc = nil
+	file_home_proto_goTypes = nil
+	file_home_proto_depIdxs = nil
 }
@@ -6,16 +6,21 @@ import (
 	"log"
 
 	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/base/baserpc"
 
 	pb "github.com/hulklab/yago/example/app/modules/demo/demorpc/demopb"
 )
 
 type HomeRpc struct {
+	baserpc.BaseRpc
 }
 
 func init() {
 	homeRpc := new(HomeRpc)
-	pb.RegisterHomeServer(yago.RpcServer, homeRpc)
+	pb.RegisterHomeServer(yago.RpcRegistry, homeRpc)
+
+	// curl 'http://127.0.0.1:8080/rpc/app.demopb.Home/Hello' -H "Content-type:application/json" -XPOST -d '{"name":"zhangsan"}'
+	baserpc.RegisterHttpGateway(yago.NewHttpGroupRouter("/rpc"), "app.demopb.Home")
 }
 
 func (r *HomeRpc) Hello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {
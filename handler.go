@@ -0,0 +1,254 @@
+package yago
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	ctxType   = reflect.TypeOf((*Ctx)(nil))
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// typed handler 的请求从 path(uri tag), query(form tag), header(header tag) 和 body 绑定, 校验后调用,
+// 返回值通过 SetData 或 SetError 渲染, handler 自行写了响应时不再渲染
+type typedHandler struct {
+	fn       reflect.Value
+	name     string
+	reqType  reflect.Type
+	respType reflect.Type
+	// 请求中声明了 uri 和 header tag 的名字
+	uriNames    map[string]bool
+	headerNames map[string]bool
+}
+
+// 将 typed handler 转为 yago.HttpHandlerFunc, 支持:
+//
+//	func(c *yago.Ctx, req *XxxReq) (*XxxResp, error)
+//	func(c *yago.Ctx, req *XxxReq) error
+//	func(c *yago.Ctx) (*XxxResp, error)
+//
+// 注册路由时使用 GetTyped, PostTyped 等方法, 路由会记录请求和响应类型
+// eg. ghttp.Root.PostTyped("/demo/user/list", h.ListAction)
+func Typed(action interface{}) HttpHandlerFunc {
+	return newTypedHandler(action).handle
+}
+
+func newTypedHandler(action interface{}) *typedHandler {
+	v := reflect.ValueOf(action)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		log.Panicf("http action must be a func: %s", t)
+	}
+
+	name := runtime.FuncForPC(v.Pointer()).Name()
+
+	if t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != ctxType {
+		log.Panicf("http action %s must be like func(c *yago.Ctx, req *XxxReq) (*XxxResp, error)", name)
+	}
+
+	th := &typedHandler{fn: v, name: name}
+
+	if t.NumIn() == 2 {
+		th.reqType = t.In(1)
+		if th.reqType.Kind() != reflect.Ptr || th.reqType.Elem().Kind() != reflect.Struct {
+			log.Panicf("http action %s request must be a pointer to struct: %s", name, th.reqType)
+		}
+		th.uriNames = taggedNames(th.reqType.Elem(), "uri")
+		th.headerNames = taggedNames(th.reqType.Elem(), "header")
+	}
+
+	switch t.NumOut() {
+	case 1:
+		if t.Out(0) != errorType {
+			log.Panicf("http action %s must return error", name)
+		}
+	case 2:
+		if t.Out(1) != errorType {
+			log.Panicf("http action %s must return (resp, error)", name)
+		}
+		th.respType = t.Out(0)
+	default:
+		log.Panicf("http action %s must return (resp, error) or error", name)
+	}
+
+	return th
+}
+
+func (th *typedHandler) handle(c *Ctx) {
+	in := []reflect.Value{reflect.ValueOf(c)}
+
+	if th.reqType != nil {
+		req := reflect.New(th.reqType.Elem())
+		if err := th.bind(c, req.Interface()); err != nil {
+			c.SetError(err)
+			return
+		}
+		in = append(in, req)
+	}
+
+	out := th.fn.Call(in)
+
+	var data interface{}
+	if len(out) == 2 && !isNilValue(out[0]) {
+		data = out[0].Interface()
+	}
+
+	var err error
+	if e := out[len(out)-1].Interface(); e != nil {
+		err = e.(error)
+	}
+
+	// handler 自行写了响应
+	if err == nil && data == nil && c.Writer.Written() {
+		return
+	}
+
+	if err != nil {
+		c.SetError(err)
+		return
+	}
+
+	c.SetData(data)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// 声明了 tag 的名字, 包括嵌套结构体中的字段
+func taggedNames(t reflect.Type, tag string) map[string]bool {
+	names := make(map[string]bool)
+	collectTaggedNames(t, tag, names, make(map[reflect.Type]bool))
+	return names
+}
+
+func collectTaggedNames(t reflect.Type, tag string, names map[string]bool, visited map[reflect.Type]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := strings.Split(f.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			names[name] = true
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			collectTaggedNames(ft, tag, names, visited)
+		}
+	}
+}
+
+// MapFormWithTag 对没有 tag 的字段使用字段名绑定, 只保留声明了 tag 的名字,
+// 避免 body 中的 Token, Origin 等字段被同名的 header 或路由参数填充
+func filterTaggedValues(values map[string][]string, names map[string]bool) map[string][]string {
+	filtered := make(map[string][]string, len(names))
+	for name := range names {
+		if v, ok := values[name]; ok {
+			filtered[name] = v
+		}
+	}
+	return filtered
+}
+
+// 依次从 path, query, header, body 绑定, 全部绑定后再校验
+func (th *typedHandler) bind(c *Ctx, req interface{}) error {
+	if len(c.Params) > 0 && len(th.uriNames) > 0 {
+		params := make(map[string][]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		if err := binding.MapFormWithTag(req, filterTaggedValues(params, th.uriNames), "uri"); err != nil {
+			return NewErr(ErrParam, err.Error())
+		}
+	}
+
+	if err := binding.MapFormWithTag(req, c.Request.URL.Query(), "form"); err != nil {
+		return NewErr(ErrParam, err.Error())
+	}
+
+	// header tag 可以是 X-Token 或 x-token
+	if len(th.headerNames) > 0 {
+		headers := make(map[string][]string, len(c.Request.Header)*2)
+		for k, v := range c.Request.Header {
+			headers[k] = v
+			headers[strings.ToLower(k)] = v
+		}
+		if err := binding.MapFormWithTag(req, filterTaggedValues(headers, th.headerNames), "header"); err != nil {
+			return NewErr(ErrParam, err.Error())
+		}
+	}
+
+	if err := bindTypedBody(c, req); err != nil {
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return binding.Validator.ValidateStruct(req)
+}
+
+func bindTypedBody(c *Ctx, req interface{}) error {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+
+	switch c.ContentType() {
+	case gin.MIMEJSON:
+		decoder := json.NewDecoder(c.Request.Body)
+		if binding.EnableDecoderUseNumber {
+			decoder.UseNumber()
+		}
+		if binding.EnableDecoderDisallowUnknownFields {
+			decoder.DisallowUnknownFields()
+		}
+		if err := decoder.Decode(req); err != nil && err != io.EOF {
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				return err
+			}
+			return NewErr(ErrParam, err.Error())
+		}
+	case gin.MIMEXML, gin.MIMEXML2:
+		if err := xml.NewDecoder(c.Request.Body).Decode(req); err != nil && err != io.EOF {
+			return NewErr(ErrParam, err.Error())
+		}
+	case gin.MIMEPOSTForm:
+		if err := c.Request.ParseForm(); err != nil {
+			return NewErr(ErrParam, err.Error())
+		}
+		if err := binding.MapFormWithTag(req, c.Request.PostForm, "form"); err != nil {
+			return NewErr(ErrParam, err.Error())
+		}
+	case gin.MIMEMultipartPOSTForm:
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			return NewErr(ErrParam, err.Error())
+		}
+		if err := binding.MapFormWithTag(req, c.Request.MultipartForm.Value, "form"); err != nil {
+			return NewErr(ErrParam, err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package yago
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// go test -v . -test.run TestTyped
+
+type typedTestReq struct {
+	Id    int    `uri:"id"`
+	Page  int    `form:"page"`
+	Token string `header:"X-Token" binding:"required"`
+	// query 和 body 都有时, body 优先
+	Name string `form:"name" json:"name" binding:"required"`
+	Age  int    `form:"age" json:"age" binding:"gte=0,lte=150"`
+}
+
+type typedTestResp struct {
+	Req  *typedTestReq `json:"req"`
+	From string        `json:"from"`
+}
+
+type typedTestBody struct {
+	Errno  int            `json:"errno"`
+	Errmsg string         `json:"errmsg"`
+	Data   *typedTestResp `json:"data"`
+}
+
+func newTypedTestEngine(method, path string, h HttpHandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Handle(method, path, func(c *gin.Context) {
+		h(&Ctx{Context: c})
+	})
+	return e
+}
+
+func doTypedTest(e *gin.Engine, method, target, contentType, body string, headers map[string]string) *typedTestBody {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	res := new(typedTestBody)
+	_ = json.Unmarshal(w.Body.Bytes(), res)
+	return res
+}
+
+func echoTypedTest(c *Ctx, req *typedTestReq) (*typedTestResp, error) {
+	return &typedTestResp{Req: req, From: "typed"}, nil
+}
+
+func TestTyped_Bind(t *testing.T) {
+	e := newTypedTestEngine(http.MethodPost, "/user/:id", Typed(echoTypedTest))
+	token := map[string]string{"X-Token": "abc"}
+
+	res := doTypedTest(e, http.MethodPost, "/user/12?page=3&name=query", gin.MIMEJSON, `{"name":"body","age":20}`, token)
+	if res.Errno != 0 || res.Data == nil {
+		t.Fatalf("bind json got %+v", res)
+	}
+	want := typedTestReq{Id: 12, Page: 3, Token: "abc", Name: "body", Age: 20}
+	if *res.Data.Req != want {
+		t.Errorf("bind json got %+v, want %+v", *res.Data.Req, want)
+	}
+
+	// 没有 body 时使用 query
+	res = doTypedTest(e, http.MethodPost, "/user/12?name=query", "", "", token)
+	if res.Errno != 0 || res.Data.Req.Name != "query" {
+		t.Errorf("bind query got %+v", res)
+	}
+
+	form := url.Values{"name": {"form"}, "age": {"30"}}.Encode()
+	res = doTypedTest(e, http.MethodPost, "/user/12?name=query", gin.MIMEPOSTForm, form, map[string]string{"x-token": "lower"})
+	if res.Errno != 0 || res.Data.Req.Name != "form" || res.Data.Req.Age != 30 || res.Data.Req.Token != "lower" {
+		t.Errorf("bind form got %+v", res)
+	}
+}
+
+// 没有 header 和 uri tag 的字段不从 header 和路由参数绑定
+func TestTyped_BindUntagged(t *testing.T) {
+	type untaggedReq struct {
+		Id     int    `json:"id"`
+		Token  string `json:"token"`
+		Origin string `json:"origin"`
+		Sign   string `header:"X-Sign"`
+	}
+
+	var got untaggedReq
+	e := newTypedTestEngine(http.MethodPost, "/user/:Id", Typed(func(c *Ctx, req *untaggedReq) error {
+		got = *req
+		return nil
+	}))
+
+	doTypedTest(e, http.MethodPost, "/user/12", gin.MIMEJSON, `{"token":"body"}`, map[string]string{"Origin": "http://a.com", "Token": "header", "X-Sign": "s"})
+	want := untaggedReq{Token: "body", Sign: "s"}
+	if got != want {
+		t.Errorf("bind untagged got %+v, want %+v", got, want)
+	}
+}
+
+func TestTyped_Validate(t *testing.T) {
+	e := newTypedTestEngine(http.MethodPost, "/user/:id", Typed(echoTypedTest))
+	token := map[string]string{"X-Token": "abc"}
+
+	cases := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		headers     map[string]string
+	}{
+		{"header required", "/user/1", gin.MIMEJSON, `{"name":"a"}`, nil},
+		{"body required", "/user/1", gin.MIMEJSON, `{"age":1}`, token},
+		{"body range", "/user/1", gin.MIMEJSON, `{"name":"a","age":200}`, token},
+		{"body type", "/user/1", gin.MIMEJSON, `{"name":1}`, token},
+		{"body syntax", "/user/1", gin.MIMEJSON, `{"name":`, token},
+		{"uri type", "/user/abc", gin.MIMEJSON, `{"name":"a"}`, token},
+		{"query type", "/user/1?page=x", gin.MIMEJSON, `{"name":"a"}`, token},
+	}
+
+	for _, c := range cases {
+		res := doTypedTest(e, http.MethodPost, c.target, c.contentType, c.body, c.headers)
+		if res.Errno != ErrParam.Code() {
+			t.Errorf("%s got errno %d, want %d", c.name, res.Errno, ErrParam.Code())
+		}
+		if res.Data != nil {
+			t.Errorf("%s should not call handler", c.name)
+		}
+	}
+}
+
+func TestTyped_Render(t *testing.T) {
+	errTest := Err("1001=test error")
+
+	e := gin.New()
+	e.GET("/err", func(c *gin.Context) {
+		Typed(func(c *Ctx) (*typedTestResp, error) {
+			return nil, errTest
+		})(&Ctx{Context: c})
+	})
+	e.GET("/written", func(c *gin.Context) {
+		Typed(func(c *Ctx) error {
+			c.String(http.StatusOK, "raw")
+			return nil
+		})(&Ctx{Context: c})
+	})
+	e.GET("/nil", func(c *gin.Context) {
+		Typed(func(c *Ctx) (*typedTestResp, error) {
+			return nil, nil
+		})(&Ctx{Context: c})
+	})
+
+	if res := doTypedTest(e, http.MethodGet, "/err", "", "", nil); res.Errno != 1001 || res.Errmsg != "test error" {
+		t.Errorf("error got %+v", res)
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/written", nil))
+	if w.Body.String() != "raw" {
+		t.Errorf("handler written response got %s", w.Body.String())
+	}
+
+	if res := doTypedTest(e, http.MethodGet, "/nil", "", "", nil); res.Errno != 0 || res.Data != nil {
+		t.Errorf("nil response got %+v", res)
+	}
+}
+
+func TestTyped_Router(t *testing.T) {
+	g := new(HttpGroupRouter)
+	mw := func(c *Ctx) {}
+
+	r := g.PostTyped("/user/:id", echoTypedTest, mw)
+	if len(r.Actions) != 2 {
+		t.Errorf("typed router actions got %d", len(r.Actions))
+	}
+	if r.RequestType != reflect.TypeOf(&typedTestReq{}) || r.ResponseType != reflect.TypeOf(&typedTestResp{}) {
+		t.Errorf("typed router got %v %v", r.RequestType, r.ResponseType)
+	}
+	if !strings.HasSuffix(r.handlerName, "echoTypedTest") {
+		t.Errorf("typed router handler name got %s", r.handlerName)
+	}
+
+	r = g.HeadTyped("/user/:id", echoTypedTest)
+	if r.Method != http.MethodHead || r.RequestType != reflect.TypeOf(&typedTestReq{}) {
+		t.Errorf("head typed router got %s %v", r.Method, r.RequestType)
+	}
+
+	r = g.OptionsTyped("/user/:id", echoTypedTest)
+	if r.Method != http.MethodOptions || r.RequestType != reflect.TypeOf(&typedTestReq{}) {
+		t.Errorf("options typed router got %s %v", r.Method, r.RequestType)
+	}
+
+	r = g.Get("/user/:id", mw, Typed(echoTypedTest))
+	if r.RequestType != nil || r.ResponseType != nil {
+		t.Errorf("untyped router got %v %v", r.RequestType, r.ResponseType)
+	}
+}
+
+func TestTyped_Invalid(t *testing.T) {
+	actions := []interface{}{
+		"not func",
+		func() error { return nil },
+		func(c *Ctx, req typedTestReq) error { return nil },
+		func(c *Ctx, req *int) error { return nil },
+		func(c *Ctx) {},
+		func(c *Ctx) (*typedTestResp, int) { return nil, 0 },
+		func(c *Ctx) (*typedTestResp, error, error) { return nil, nil, nil },
+	}
+
+	for _, action := range actions {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Typed(%T) should panic", action)
+				}
+			}()
+			Typed(action)
+		}()
+	}
+}
@@ -0,0 +1,157 @@
+package yago
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 组件实现此接口后, 通过 yago.Component.Ins 创建的实例会参与 readiness 检查
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+type HealthStatus struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Consume int64  `json:"consume"`
+}
+
+type HealthReport struct {
+	Status     string                   `json:"status"`
+	Components map[string]*HealthStatus `json:"components,omitempty"`
+}
+
+const (
+	healthStatusOk      = "ok"
+	healthStatusFail    = "fail"
+	healthStatusTimeout = "timeout"
+	healthStatusClosing = "closing"
+)
+
+// 检查已创建的组件, 未使用过的组件不会被检查
+func (c *components) HealthCheck(ctx context.Context) map[string]*HealthStatus {
+	checkers := make(map[string]HealthChecker)
+	c.m.Range(func(key, value interface{}) bool {
+		if v, ok := value.(HealthChecker); ok {
+			checkers[fmt.Sprint(key)] = v
+		}
+		return true
+	})
+
+	result := make(map[string]*HealthStatus, len(checkers))
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for name, checker := range checkers {
+		result[name] = &HealthStatus{Status: healthStatusTimeout}
+
+		wg.Add(1)
+		go func(name string, checker HealthChecker) {
+			defer wg.Done()
+
+			begin := time.Now()
+			err := checker.HealthCheck(ctx)
+
+			status := &HealthStatus{Status: healthStatusOk, Consume: time.Since(begin).Nanoseconds() / 1e6}
+			if err != nil {
+				status.Status = healthStatusFail
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			result[name] = status
+			mu.Unlock()
+		}(name, checker)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// checker which does not respect ctx is marked as timeout
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	report := make(map[string]*HealthStatus, len(result))
+	for name, status := range result {
+		report[name] = status
+	}
+
+	return report
+}
+
+// liveness, 进程能处理请求即为健康, 不依赖组件
+func (a *App) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, &HealthReport{Status: healthStatusOk})
+}
+
+// readiness, app 开始关闭或任一组件检查失败时返回 503
+func (a *App) Readyz(c *gin.Context) {
+	if a.IsClosing() {
+		c.JSON(http.StatusServiceUnavailable, &HealthReport{Status: healthStatusClosing})
+		return
+	}
+
+	timeout := 3 * time.Second
+	if Config.IsSet("app.http_readyz_timeout") {
+		timeout = Config.GetDuration("app.http_readyz_timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	report := &HealthReport{
+		Status:     healthStatusOk,
+		Components: Component.HealthCheck(ctx),
+	}
+
+	code := http.StatusOK
+	for _, status := range report.Components {
+		if status.Status != healthStatusOk {
+			report.Status = healthStatusFail
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(code, report)
+}
+
+func (a *App) IsClosing() bool {
+	return atomic.LoadInt32(&a.closing) == 1
+}
+
+func (a *App) loadHealthRouter() {
+	if !a.HttpHealthOn {
+		return
+	}
+
+	healthzRoute := "/healthz"
+	if Config.IsSet("app.http_healthz_route") {
+		healthzRoute = Config.GetString("app.http_healthz_route")
+	}
+
+	readyzRoute := "/readyz"
+	if Config.IsSet("app.http_readyz_route") {
+		readyzRoute = Config.GetString("app.http_readyz_route")
+	}
+
+	a.httpEngine.GET(healthzRoute, a.Healthz)
+	a.httpEngine.GET(readyzRoute, a.Readyz)
+
+	debugf("[HTTP] %-6s %-25s --> %s\n", http.MethodGet, healthzRoute, "Healthz")
+	debugf("[HTTP] %-6s %-25s --> %s\n", http.MethodGet, readyzRoute, "Readyz")
+}
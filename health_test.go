@@ -0,0 +1,112 @@
+package yago
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// go test -v . -test.run "TestReadyz|TestClose"
+
+type healthTestChecker func(ctx context.Context) error
+
+func (f healthTestChecker) HealthCheck(ctx context.Context) error {
+	return f(ctx)
+}
+
+func readyzTest(a *App) (int, *HealthReport) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	a.Readyz(c)
+
+	report := new(HealthReport)
+	_ = json.Unmarshal(w.Body.Bytes(), report)
+	return w.Code, report
+}
+
+func TestReadyz(t *testing.T) {
+	Config.Set("app.http_readyz_timeout", "50ms")
+	defer Config.Set("app.http_readyz_timeout", "3s")
+
+	cases := []struct {
+		name    string
+		checker healthTestChecker
+		closing int32
+		code    int
+		status  string
+	}{
+		{"ok", func(ctx context.Context) error { return nil }, 0, http.StatusOK, healthStatusOk},
+		{"fail", func(ctx context.Context) error { return errors.New("down") }, 0, http.StatusServiceUnavailable, healthStatusFail},
+		{"timeout", func(ctx context.Context) error { time.Sleep(time.Second); return nil }, 0, http.StatusServiceUnavailable, healthStatusFail},
+		{"closing", func(ctx context.Context) error { return nil }, 1, http.StatusServiceUnavailable, healthStatusClosing},
+	}
+
+	for _, c := range cases {
+		Component.m.Store("health_test", c.checker)
+
+		a := &App{closing: c.closing}
+		code, report := readyzTest(a)
+		if code != c.code || report.Status != c.status {
+			t.Errorf("%s got %d %+v, want %d %s", c.name, code, report, c.code, c.status)
+		}
+
+		Component.m.Delete("health_test")
+	}
+}
+
+type closeTestServer struct {
+	stoppedAt atomic.Value
+}
+
+func (s *closeTestServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *closeTestServer) Stop(ctx context.Context) error {
+	s.stoppedAt.Store(time.Now())
+	return nil
+}
+
+// Close 后 readyz 立即失败, 等待 ready_drain_wait 后再关闭服务
+func TestClose_ReadyDrain(t *testing.T) {
+	drain := 200 * time.Millisecond
+	Config.Set("app.ready_drain_wait", drain.String())
+	defer Config.Set("app.ready_drain_wait", "0s")
+
+	a := NewApp()
+	s := new(closeTestServer)
+	a.AddServer("Test", s)
+
+	if code, _ := readyzTest(a); code != http.StatusOK {
+		t.Fatalf("readyz before close got %d", code)
+	}
+
+	begin := time.Now()
+	done := make(chan struct{})
+	go func() {
+		a.Close()
+		close(done)
+	}()
+
+	time.Sleep(drain / 4)
+	if code, report := readyzTest(a); code != http.StatusServiceUnavailable || report.Status != healthStatusClosing {
+		t.Errorf("readyz after close got %d %+v", code, report)
+	}
+	if s.stoppedAt.Load() != nil {
+		t.Error("server should not be stopped while draining")
+	}
+
+	<-done
+	stoppedAt, _ := s.stoppedAt.Load().(time.Time)
+	if stoppedAt.Sub(begin) < drain {
+		t.Errorf("server stopped %s after close, want at least %s", stoppedAt.Sub(begin), drain)
+	}
+}
@@ -0,0 +1,270 @@
+package yago
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	validatorv10 "github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+const ctxLangKey = "__Lang__"
+
+// 语言包, 文件名为语言, 如 en.toml, zh.json
+// [errors] 按错误码配置错误信息, [validation] 按校验 tag 配置校验信息, {0} 为字段名, {1} 为参数
+type I18nCatalog struct {
+	Errors     map[int]string
+	Validation map[string]string
+}
+
+var (
+	i18nCatalogs    = make(map[string]*I18nCatalog)
+	i18nQueryKey    = "lang"
+	i18nHeaderKey   = "X-Lang"
+	i18nDefaultLang = "zh"
+)
+
+func initI18n() {
+	i18nDefaultLang = GetLang()
+
+	if Config.IsSet("app.i18n_query") {
+		i18nQueryKey = Config.GetString("app.i18n_query")
+	}
+	if Config.IsSet("app.i18n_header") {
+		i18nHeaderKey = Config.GetString("app.i18n_header")
+	}
+
+	dir := Config.GetString("app.i18n_dir")
+	if dir == "" {
+		return
+	}
+
+	if err := LoadI18nCatalogs(dir); err != nil {
+		fatalln("load i18n catalogs err:", err.Error())
+	}
+}
+
+// 加载目录下的 toml, json, yaml 语言包, 同一语言的多个文件会合并
+func LoadI18nCatalogs(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(f.Name())
+		switch ext {
+		case ".toml", ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		lang := strings.ToLower(strings.TrimSuffix(f.Name(), ext))
+		if err := loadI18nCatalog(lang, filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func loadI18nCatalog(lang, file string) error {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+
+	catalog, ok := i18nCatalogs[lang]
+	if !ok {
+		catalog = &I18nCatalog{
+			Errors:     make(map[int]string),
+			Validation: make(map[string]string),
+		}
+		i18nCatalogs[lang] = catalog
+	}
+
+	for k, msg := range v.GetStringMapString("errors") {
+		code, err := strconv.Atoi(k)
+		if err != nil {
+			return fmt.Errorf("i18n catalog %s: errors key %s is not errno", file, k)
+		}
+		catalog.Errors[code] = msg
+	}
+
+	for tag, msg := range v.GetStringMapString("validation") {
+		catalog.Validation[tag] = msg
+	}
+
+	return nil
+}
+
+// 已加载语言包的语言
+func GetI18nLangs() []string {
+	langs := make([]string, 0, len(i18nCatalogs))
+	for lang := range i18nCatalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// 将错误信息翻译成指定语言, 调用时自定义的错误信息不翻译:
+// 已登记的错误码, 只翻译错误信息为登记信息的错误;
+// 未登记的错误码, 如 var ErrX = yago.Err("1001=..."), 默认语言的语言包中有该错误码时, 只翻译错误信息与之相同的错误, 否则都翻译
+// eg. yago.LocalizeErr("en", g.ErrUserNotFound)
+func LocalizeErr(lang string, ye Err) Err {
+	catalog := getI18nCatalog(lang)
+	if catalog == nil {
+		return ye
+	}
+
+	code := ye.Code()
+	msg, ok := catalog.Errors[code]
+	if !ok {
+		return ye
+	}
+
+	if ec, ok := GetErrCode(code); ok {
+		if ec.Message != ye.Error() {
+			return ye
+		}
+	} else if def := getI18nCatalog(i18nDefaultLang); def != nil {
+		if defMsg, ok := def.Errors[code]; ok && defMsg != ye.Error() {
+			return ye
+		}
+	}
+
+	return NewErr(code, msg)
+}
+
+// 将校验错误翻译成指定语言, 语言包中没有的 tag 使用 validator 内置的翻译
+func LocalizeFieldErr(lang string, fe validatorv10.FieldError) string {
+	if catalog := getI18nCatalog(lang); catalog != nil {
+		if msg, ok := catalog.Validation[strings.ToLower(fe.Tag())]; ok {
+			return strings.NewReplacer("{0}", fe.Field(), "{1}", fe.Param()).Replace(msg)
+		}
+	}
+
+	return fe.Translate(GetTranslator(lang))
+}
+
+// zh-CN 找不到时使用 zh
+func getI18nCatalog(lang string) *I18nCatalog {
+	lang = strings.ToLower(lang)
+	if catalog, ok := i18nCatalogs[lang]; ok {
+		return catalog
+	}
+
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		return i18nCatalogs[lang[:i]]
+	}
+
+	return nil
+}
+
+// 请求的语言, 优先级: query 参数 > header > Accept-Language > app.lang
+func (c *Ctx) GetLang() string {
+	if v, ok := c.Get(ctxLangKey); ok {
+		return v.(string)
+	}
+
+	lang := c.resolveLang()
+	c.Set(ctxLangKey, lang)
+
+	return lang
+}
+
+func (c *Ctx) resolveLang() string {
+	if c.Request != nil {
+		if lang := matchLang(c.Query(i18nQueryKey)); lang != "" {
+			return lang
+		}
+
+		if lang := matchLang(c.GetHeader(i18nHeaderKey)); lang != "" {
+			return lang
+		}
+
+		for _, tag := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+			if lang := matchLang(tag); lang != "" {
+				return lang
+			}
+		}
+	}
+
+	return i18nDefaultLang
+}
+
+// 返回已支持的语言, 语言包或 validator 翻译, 不支持时返回空
+func matchLang(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return ""
+	}
+
+	candidates := []string{tag}
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		candidates = append(candidates, tag[:i])
+	}
+
+	for _, lang := range candidates {
+		if _, ok := i18nCatalogs[lang]; ok {
+			return lang
+		}
+		if _, ok := uni.FindTranslator(lang); ok {
+			return lang
+		}
+	}
+
+	return ""
+}
+
+// 按 q 值从高到低返回, eg. zh-CN,zh;q=0.9,en;q=0.8
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	list := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q > 0 {
+			list = append(list, weighted{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+
+	tags := make([]string, 0, len(list))
+	for _, w := range list {
+		tags = append(tags, w.tag)
+	}
+
+	return tags
+}
@@ -0,0 +1,56 @@
+package yago
+
+import (
+	"testing"
+)
+
+// go test -v . -test.run TestLocalizeErr
+
+func TestLocalizeErr(t *testing.T) {
+	errNotFound := RegisterErr("test", 91001, "用户不存在")
+
+	i18nCatalogs["test-en"] = &I18nCatalog{
+		Errors: map[int]string{
+			91001: "User not found",
+			91002: "Localized message",
+		},
+		Validation: make(map[string]string),
+	}
+	i18nCatalogs["test-zh"] = &I18nCatalog{
+		Errors:     map[int]string{91004: "默认信息"},
+		Validation: make(map[string]string),
+	}
+	i18nCatalogs["test-en"].Errors[91004] = "Default message"
+
+	defaultLang := i18nDefaultLang
+	i18nDefaultLang = "test-zh"
+	defer func() {
+		delete(i18nCatalogs, "test-en")
+		delete(i18nCatalogs, "test-zh")
+		i18nDefaultLang = defaultLang
+	}()
+
+	cases := []struct {
+		name string
+		err  Err
+		want string
+	}{
+		{"registered with default message", errNotFound, "User not found"},
+		{"registered with custom message", NewErr(91001, "用户 1 不存在"), "用户 1 不存在"},
+		{"unregistered", Err("91002=用户未找到"), "Localized message"},
+		{"unregistered with default message", Err("91004=默认信息"), "Default message"},
+		{"unregistered with custom message", NewErr(91004, "订单 1 的默认信息"), "订单 1 的默认信息"},
+		{"not in catalog", Err("91003=没有翻译"), "没有翻译"},
+	}
+
+	for _, c := range cases {
+		got := LocalizeErr("test-en", c.err)
+		if got.Code() != c.err.Code() || got.Error() != c.want {
+			t.Errorf("%s got %s, want %d=%s", c.name, got.String(), c.err.Code(), c.want)
+		}
+	}
+
+	if got := LocalizeErr("unknown", errNotFound); got != errNotFound {
+		t.Errorf("unknown lang got %s", got.String())
+	}
+}
@@ -0,0 +1,216 @@
+package semalib
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrLimitExceeded = errors.New("semalib: concurrency limit exceeded")
+
+// 请求的优先级, 达到上限前先拒绝低优先级的请求
+// 数值越大越重要, 零值为 PriorityNormal
+type Priority int
+
+const (
+	PriorityBatch    Priority = -1
+	PriorityNormal   Priority = 0
+	PriorityCritical Priority = 1
+)
+
+const (
+	// 每收集这么多个延迟样本调整一次上限
+	adaptiveSampleSize = 20
+	// 基线延迟的平滑窗口, 单位为调整次数
+	adaptiveLongWindow = 60
+	// 延迟升高到基线的多少倍以内不降低上限
+	adaptiveTolerance = 1.5
+	// 每次调整时新上限的权重
+	adaptiveSmoothing = 0.2
+)
+
+type LimiterConfig struct {
+	// 并发上限, 开启 Adaptive 时为初始值
+	Limit int
+	// 根据延迟自动调整上限, 延迟相对基线升高时降低上限
+	Adaptive bool
+	MinLimit int
+	MaxLimit int
+	// 达到上限时最多等待的时间, 为 0 时直接拒绝
+	MaxWait time.Duration
+	// 普通和批量请求最多使用上限的比例, 余下的留给重要请求, 默认 0.9 和 0.5
+	NormalRatio float64
+	BatchRatio  float64
+}
+
+// 并发限制, 超出上限时快速失败, 避免请求堆积
+type Limiter struct {
+	conf LimiterConfig
+
+	mu       sync.Mutex
+	inflight int
+	limit    float64
+	// 有请求结束时关闭, 唤醒等待的请求
+	released chan struct{}
+
+	// 自适应调整的状态, 延迟单位为纳秒
+	longRTT     float64
+	sampleSum   float64
+	sampleCount int
+	maxInflight int
+}
+
+func NewLimiter(conf LimiterConfig) *Limiter {
+	if conf.Limit <= 0 {
+		conf.Limit = 100
+	}
+	if conf.MinLimit <= 0 {
+		conf.MinLimit = 1
+	}
+	if conf.MaxLimit < conf.Limit {
+		conf.MaxLimit = conf.Limit
+		if conf.Adaptive {
+			conf.MaxLimit = conf.Limit * 10
+		}
+	}
+	if conf.NormalRatio <= 0 || conf.NormalRatio > 1 {
+		conf.NormalRatio = 0.9
+	}
+	if conf.BatchRatio <= 0 || conf.BatchRatio > 1 {
+		conf.BatchRatio = 0.5
+	}
+
+	return &Limiter{
+		conf:     conf,
+		limit:    float64(conf.Limit),
+		released: make(chan struct{}),
+	}
+}
+
+// 获取许可, 返回的 release 在请求结束时调用
+// 超出上限且等待超时返回 ErrLimitExceeded, 调用方的 ctx 取消或超时返回 ctx.Err(), 不算作过载
+func (l *Limiter) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	var timeout <-chan time.Time
+
+	for {
+		l.mu.Lock()
+		if float64(l.inflight) < l.threshold(p) {
+			l.inflight++
+			if l.inflight > l.maxInflight {
+				l.maxInflight = l.inflight
+			}
+			l.mu.Unlock()
+
+			start := time.Now()
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					l.release(time.Since(start))
+				})
+			}, nil
+		}
+
+		if l.conf.MaxWait <= 0 {
+			l.mu.Unlock()
+			return nil, ErrLimitExceeded
+		}
+
+		released := l.released
+		l.mu.Unlock()
+
+		if timeout == nil {
+			timer := time.NewTimer(l.conf.MaxWait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case <-released:
+		case <-timeout:
+			return nil, ErrLimitExceeded
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *Limiter) threshold(p Priority) float64 {
+	switch p {
+	case PriorityCritical:
+		return l.limit
+	case PriorityBatch:
+		return math.Max(1, math.Floor(l.limit*l.conf.BatchRatio))
+	default:
+		return math.Max(1, math.Floor(l.limit*l.conf.NormalRatio))
+	}
+}
+
+func (l *Limiter) release(rtt time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	close(l.released)
+	l.released = make(chan struct{})
+
+	if l.conf.Adaptive {
+		l.observe(rtt)
+	}
+}
+
+// 参考 Netflix concurrency-limits 的 gradient 算法
+// 短期平均延迟高于长期基线时按比例降低上限, 否则以 sqrt(limit) 的速度增长
+func (l *Limiter) observe(rtt time.Duration) {
+	l.sampleSum += float64(rtt)
+	l.sampleCount++
+	if l.sampleCount < adaptiveSampleSize {
+		return
+	}
+
+	shortRTT := l.sampleSum / float64(l.sampleCount)
+	maxInflight := l.maxInflight
+	l.sampleSum, l.sampleCount, l.maxInflight = 0, 0, l.inflight
+
+	if shortRTT <= 0 {
+		return
+	}
+
+	if l.longRTT == 0 {
+		l.longRTT = shortRTT
+	} else {
+		l.longRTT += (shortRTT - l.longRTT) * 2 / (adaptiveLongWindow + 1)
+	}
+
+	// 延迟明显下降后基线跟随下降
+	if l.longRTT/shortRTT > 2 {
+		l.longRTT *= 0.95
+	}
+
+	// 请求量远低于上限时无法判断上限是否合适
+	if float64(maxInflight) < l.limit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, adaptiveTolerance*l.longRTT/shortRTT))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	newLimit = l.limit*(1-adaptiveSmoothing) + newLimit*adaptiveSmoothing
+
+	l.limit = math.Max(float64(l.conf.MinLimit), math.Min(float64(l.conf.MaxLimit), newLimit))
+}
+
+// 当前的并发上限
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+func (l *Limiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inflight
+}
@@ -0,0 +1,139 @@
+package semalib
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Priority(t *testing.T) {
+	if !(PriorityBatch < PriorityNormal && PriorityNormal < PriorityCritical) {
+		t.Fatal("priority should be ordered by importance")
+	}
+	var zero Priority
+	if zero != PriorityNormal {
+		t.Errorf("zero priority got %d, want PriorityNormal", zero)
+	}
+
+	l := NewLimiter(LimiterConfig{Limit: 10})
+
+	releases := make([]func(), 0)
+	acquire := func(p Priority) bool {
+		release, err := l.Acquire(context.Background(), p)
+		if err != nil {
+			return false
+		}
+		releases = append(releases, release)
+		return true
+	}
+
+	// 批量请求最多占用一半
+	for i := 0; i < 5; i++ {
+		if !acquire(PriorityBatch) {
+			t.Fatalf("batch request %d should pass", i)
+		}
+	}
+	if acquire(PriorityBatch) {
+		t.Error("batch request should be rejected over 50%")
+	}
+
+	for i := 0; i < 4; i++ {
+		if !acquire(PriorityNormal) {
+			t.Fatalf("normal request %d should pass", i)
+		}
+	}
+	if acquire(PriorityNormal) {
+		t.Error("normal request should be rejected over 90%")
+	}
+
+	if !acquire(PriorityCritical) {
+		t.Error("critical request should pass")
+	}
+	if acquire(PriorityCritical) {
+		t.Error("critical request should be rejected over limit")
+	}
+
+	// 重复调用 release 只释放一次
+	releases[0]()
+	releases[0]()
+	if got := l.Inflight(); got != 9 {
+		t.Errorf("inflight got %d, want 9", got)
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	l := NewLimiter(LimiterConfig{Limit: 1, MaxWait: 200 * time.Millisecond})
+
+	release, err := l.Acquire(context.Background(), PriorityCritical)
+	if err != nil {
+		t.Fatalf("acquire err: %s", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release()
+	}()
+
+	release2, err := l.Acquire(context.Background(), PriorityCritical)
+	if err != nil {
+		t.Fatalf("acquire after release err: %s", err)
+	}
+
+	begin := time.Now()
+	if _, err := l.Acquire(context.Background(), PriorityCritical); err != ErrLimitExceeded {
+		t.Errorf("acquire timeout got %v", err)
+	}
+	if time.Since(begin) < 200*time.Millisecond {
+		t.Error("acquire should wait MaxWait")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, PriorityCritical); err != context.DeadlineExceeded {
+		t.Errorf("acquire with canceled ctx got %v", err)
+	}
+
+	release2()
+}
+
+func TestLimiter_Adaptive(t *testing.T) {
+	l := NewLimiter(LimiterConfig{Limit: 100, Adaptive: true, MinLimit: 10, MaxLimit: 200})
+
+	observe := func(rtt time.Duration, rounds int) {
+		for i := 0; i < rounds*adaptiveSampleSize; i++ {
+			l.maxInflight = l.Limit()
+			l.observe(rtt)
+		}
+	}
+
+	// 延迟稳定时上限增长
+	observe(10*time.Millisecond, 20)
+	grown := l.Limit()
+	if grown <= 100 {
+		t.Errorf("limit should grow with stable latency, got %d", grown)
+	}
+
+	// 延迟升高后上限下降
+	observe(100*time.Millisecond, 20)
+	if got := l.Limit(); got >= grown {
+		t.Errorf("limit should drop with high latency, got %d, before %d", got, grown)
+	}
+
+	// 不低于下限
+	l = NewLimiter(LimiterConfig{Limit: 20, Adaptive: true, MinLimit: 15})
+	observe(10*time.Millisecond, 5)
+	observe(time.Second, 10)
+	if got := l.Limit(); got != 15 {
+		t.Errorf("limit should not be less than min limit, got %d", got)
+	}
+
+	// 请求量很少时不调整
+	l.maxInflight = 0
+	before := l.Limit()
+	for i := 0; i < adaptiveSampleSize; i++ {
+		l.observe(time.Millisecond)
+	}
+	if got := l.Limit(); got != before {
+		t.Errorf("limit should not change when app limited, got %d, before %d", got, before)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -42,6 +43,31 @@ func (s *semaphore) Acquire() {
 	s.wg.Add(1)
 }
 
+// 阻塞等待, ctx 结束时返回 ctx.Err()
+func (s *semaphore) AcquireContext(ctx context.Context) error {
+	select {
+	case s.channel <- struct{}{}:
+		s.wg.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 最多等待 timeout, 超时返回 false
+func (s *semaphore) AcquireTimeout(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case s.channel <- struct{}{}:
+		s.wg.Add(1)
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 func (s *semaphore) Release() {
 	<-s.channel
 	s.wg.Done()
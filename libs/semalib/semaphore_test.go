@@ -1,6 +1,7 @@
 package semalib
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -84,3 +85,24 @@ func TestErrReturnSemaphore(t *testing.T) {
 
 	fmt.Println("---------- TestErrRetrunSemaphore done ----------")
 }
+
+func TestAcquireContext(t *testing.T) {
+	sema := New(1)
+	sema.Acquire()
+
+	if sema.AcquireTimeout(50 * time.Millisecond) {
+		t.Error("acquire should timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sema.AcquireContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("acquire with ctx got %v", err)
+	}
+
+	sema.Release()
+	if err := sema.AcquireContext(context.Background()); err != nil {
+		t.Errorf("acquire after release got %v", err)
+	}
+	sema.Release()
+}
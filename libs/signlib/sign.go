@@ -0,0 +1,112 @@
+package signlib
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名相关的请求头
+const (
+	HeaderAppKey    = "X-App-Key"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+type Request struct {
+	Method string
+	Path   string
+	// query 参数, 不包含 body 中的表单
+	Params url.Values
+	// multipart 请求不计算 body, 传 nil
+	Body      []byte
+	Timestamp string
+	Nonce     string
+}
+
+// 待签名字符串, 各部分以换行连接
+//
+//	METHOD
+//	/path
+//	a=1&b=2&b=3  参数按 key 排序, 同名参数按 value 排序, 经过 url 编码
+//	hex(sha256(body))
+//	timestamp
+//	nonce
+func StringToSign(r *Request) string {
+	bodyHash := sha256.Sum256(r.Body)
+
+	return strings.Join([]string{
+		strings.ToUpper(r.Method),
+		r.Path,
+		canonicalParams(r.Params),
+		hex.EncodeToString(bodyHash[:]),
+		r.Timestamp,
+		r.Nonce,
+	}, "\n")
+}
+
+func canonicalParams(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(params))
+	for _, k := range keys {
+		values := append([]string(nil), params[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// hex(hmac-sha256(secret, StringToSign))
+func Sign(secret string, r *Request) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(StringToSign(r)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret string, r *Request, signature string) bool {
+	expected := Sign(secret, r)
+	return hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected))
+}
+
+// 秒级时间戳
+func Timestamp() string {
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+func Nonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// 时间戳与当前时间的偏差是否在 maxSkew 以内
+func CheckTimestamp(timestamp string, maxSkew time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	skew := time.Since(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+
+	return skew <= maxSkew
+}
@@ -0,0 +1,70 @@
+package signlib
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStringToSign(t *testing.T) {
+	r := &Request{
+		Method:    "post",
+		Path:      "/openapi/order",
+		Params:    url.Values{"b": {"3", "2"}, "a": {"x y"}},
+		Body:      []byte(`{"id":1}`),
+		Timestamp: "1573763091",
+		Nonce:     "abc",
+	}
+
+	want := "POST\n/openapi/order\na=x+y&b=2&b=3\n" +
+		"037c9214eef74cc3887f3a4f085b4e17d76280dafd273b0ee160c09c4ba1cfd4\n1573763091\nabc"
+	if got := StringToSign(r); got != want {
+		t.Errorf("string to sign got %q, want %q", got, want)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	r := &Request{
+		Method:    "GET",
+		Path:      "/openapi/order",
+		Params:    url.Values{"id": {"1"}},
+		Timestamp: Timestamp(),
+		Nonce:     Nonce(),
+	}
+
+	sig := Sign("secret", r)
+	if !Verify("secret", r, sig) {
+		t.Error("verify should pass")
+	}
+
+	if Verify("other", r, sig) {
+		t.Error("verify with other secret should fail")
+	}
+
+	r.Params.Set("id", "2")
+	if Verify("secret", r, sig) {
+		t.Error("verify tampered params should fail")
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.Now().Unix()
+
+	cases := []struct {
+		timestamp string
+		ok        bool
+	}{
+		{strconv.FormatInt(now, 10), true},
+		{strconv.FormatInt(now-200, 10), true},
+		{strconv.FormatInt(now+200, 10), true},
+		{strconv.FormatInt(now-400, 10), false},
+		{"abc", false},
+	}
+
+	for _, c := range cases {
+		if got := CheckTimestamp(c.timestamp, 5*time.Minute); got != c.ok {
+			t.Errorf("check timestamp %s got %v, want %v", c.timestamp, got, c.ok)
+		}
+	}
+}
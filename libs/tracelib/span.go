@@ -0,0 +1,274 @@
+package tracelib
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 与 OTLP 中的 Span.SpanKind 取值一致
+type SpanKind int
+
+const (
+	SpanKindUnspecified SpanKind = iota
+	SpanKindInternal
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// 与 OTLP 中的 Status.StatusCode 取值一致
+type StatusCode int
+
+const (
+	StatusCodeUnset StatusCode = iota
+	StatusCodeOk
+	StatusCodeError
+)
+
+type Span struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	ParentSpanID  SpanID
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	StatusCode    StatusCode
+	StatusMessage string
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// 创建 span, ctx 中有 span 或上游 trace context 时作为子 span, 否则开启新的 trace
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+
+	if parent.IsValid() {
+		span.SpanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			Sampled:    parent.Sampled,
+			TraceState: parent.TraceState,
+		}
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.SpanContext = SpanContext{
+			TraceID: newTraceID(),
+			Sampled: true,
+		}
+	}
+	span.SpanContext.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	s.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	s.mu.Lock()
+	s.StatusCode = code
+	s.StatusMessage = msg
+	s.mu.Unlock()
+}
+
+// err 为 nil 时忽略
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusCodeError, err.Error())
+}
+
+// 结束 span, 已采样的交给 exporter 导出, 重复调用无效
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+
+	if s.SpanContext.Sampled {
+		export(s)
+	}
+}
+
+// 导出已结束的 span, 字段与 OTLP 一一对应, 可以适配为 OTLP exporter
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+	Shutdown(ctx context.Context) error
+}
+
+type ExporterOption func(p *batchProcessor)
+
+// 队列长度, 队列满时丢弃新的 span, 默认 2048
+func WithMaxQueueSize(size int) ExporterOption {
+	return func(p *batchProcessor) {
+		p.queue = make(chan *Span, size)
+	}
+}
+
+// 单次导出的最大 span 数, 默认 512
+func WithMaxExportBatchSize(size int) ExporterOption {
+	return func(p *batchProcessor) {
+		p.batchSize = size
+	}
+}
+
+// 导出间隔, 默认 5s
+func WithBatchTimeout(d time.Duration) ExporterOption {
+	return func(p *batchProcessor) {
+		p.timeout = d
+	}
+}
+
+var (
+	processorMu sync.RWMutex
+	processor   *batchProcessor
+)
+
+// 设置 exporter, 已有的 exporter 会被关闭
+// eg. tracelib.SetExporter(otlpExporter, tracelib.WithBatchTimeout(time.Second))
+func SetExporter(e Exporter, opts ...ExporterOption) {
+	p := newBatchProcessor(e, opts...)
+
+	processorMu.Lock()
+	old := processor
+	processor = p
+	processorMu.Unlock()
+
+	if old != nil {
+		_ = old.shutdown(context.Background())
+	}
+}
+
+// 导出队列中剩余的 span 并关闭 exporter, app 关闭时调用
+func Shutdown(ctx context.Context) error {
+	processorMu.Lock()
+	p := processor
+	processor = nil
+	processorMu.Unlock()
+
+	if p == nil {
+		return nil
+	}
+
+	return p.shutdown(ctx)
+}
+
+func export(s *Span) {
+	processorMu.RLock()
+	defer processorMu.RUnlock()
+
+	if processor != nil {
+		processor.enqueue(s)
+	}
+}
+
+type batchProcessor struct {
+	exporter  Exporter
+	queue     chan *Span
+	batchSize int
+	timeout   time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+	once      sync.Once
+}
+
+func newBatchProcessor(e Exporter, opts ...ExporterOption) *batchProcessor {
+	p := &batchProcessor{
+		exporter:  e,
+		queue:     make(chan *Span, 2048),
+		batchSize: 512,
+		timeout:   5 * time.Second,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	go p.run()
+
+	return p
+}
+
+func (p *batchProcessor) enqueue(s *Span) {
+	select {
+	case <-p.stop:
+	case p.queue <- s:
+	default:
+		// drop when the queue is full, never block the request
+	}
+}
+
+func (p *batchProcessor) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.timeout)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, p.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		_ = p.exporter.ExportSpans(context.Background(), batch)
+		batch = make([]*Span, 0, p.batchSize)
+	}
+
+	for {
+		select {
+		case s := <-p.queue:
+			batch = append(batch, s)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stop:
+			for {
+				select {
+				case s := <-p.queue:
+					batch = append(batch, s)
+					if len(batch) >= p.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return p.exporter.Shutdown(ctx)
+}
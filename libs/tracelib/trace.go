@@ -0,0 +1,200 @@
+package tracelib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// W3C trace context, https://www.w3.org/TR/trace-context/
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+const (
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func newTraceID() (t TraceID) {
+	_, _ = rand.Read(t[:])
+	return t
+}
+
+func newSpanID() (s SpanID) {
+	_, _ = rand.Read(s[:])
+	return s
+}
+
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	// 从上游传递过来的
+	Remote bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// 格式: {version}-{trace-id}-{parent-id}-{trace-flags}
+func (sc SpanContext) Traceparent() string {
+	var flags byte
+	if sc.Sampled {
+		flags |= flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, flags)
+}
+
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return sc, errInvalidTraceparent
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// version ff is forbidden, version 00 has exactly 4 parts, higher versions may append fields
+	if len(version) != 2 || version == "ff" || (version == traceparentVersion && len(parts) != 4) {
+		return sc, errInvalidTraceparent
+	}
+
+	if len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 {
+		return sc, errInvalidTraceparent
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(traceID)); err != nil {
+		return sc, errInvalidTraceparent
+	}
+
+	if _, err := hex.Decode(sc.SpanID[:], []byte(spanID)); err != nil {
+		return sc, errInvalidTraceparent
+	}
+
+	var f [1]byte
+	if _, err := hex.Decode(f[:], []byte(flags)); err != nil {
+		return sc, errInvalidTraceparent
+	}
+
+	if !sc.IsValid() {
+		return sc, errInvalidTraceparent
+	}
+
+	sc.Sampled = f[0]&flagSampled == flagSampled
+	sc.Remote = true
+
+	return sc, nil
+}
+
+// 传递 trace context 的载体, http.Header 可以直接使用
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// 从载体中解析上游的 trace context, 解析失败时返回原 ctx
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	sc, err := ParseTraceparent(carrier.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+
+	sc.TraceState = carrier.Get(TracestateHeader)
+
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// 将 ctx 中的 trace context 写入载体, 传递给下游
+func Inject(ctx context.Context, carrier Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	carrier.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		carrier.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// 优先返回当前 span, 其次是上游传递的
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext
+	}
+
+	if ctx == nil {
+		return SpanContext{}
+	}
+
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// 用于日志, 没有时返回空串
+func TraceIDFromContext(ctx context.Context) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.TraceID.IsValid() {
+		return ""
+	}
+	return sc.TraceID.String()
+}
+
+func SpanIDFromContext(ctx context.Context) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.SpanID.IsValid() {
+		return ""
+	}
+	return sc.SpanID.String()
+}
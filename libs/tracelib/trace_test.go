@@ -0,0 +1,140 @@
+package tracelib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	var tests = []struct {
+		in      string
+		valid   bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.in)
+		if (err == nil) != tt.valid {
+			t.Errorf("ParseTraceparent(%q) err: %v, want valid: %v", tt.in, err, tt.valid)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if sc.Sampled != tt.sampled {
+			t.Errorf("ParseTraceparent(%q) sampled: %v, want: %v", tt.in, sc.Sampled, tt.sampled)
+		}
+
+		if !sc.Remote {
+			t.Errorf("ParseTraceparent(%q) should be remote", tt.in)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Set(TracestateHeader, "congo=t61rcWkgMzE")
+
+	ctx := Extract(context.Background(), in)
+	if TraceIDFromContext(ctx) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("extract trace id err: %s", TraceIDFromContext(ctx))
+	}
+
+	ctx, span := StartSpan(ctx, "GET /", SpanKindServer)
+	if span.ParentSpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("parent span id err: %s", span.ParentSpanID)
+	}
+	if SpanIDFromContext(ctx) != span.SpanContext.SpanID.String() {
+		t.Errorf("span id in ctx err: %s", SpanIDFromContext(ctx))
+	}
+
+	out := http.Header{}
+	Inject(ctx, out)
+
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + span.SpanContext.SpanID.String() + "-01"
+	if out.Get(TraceparentHeader) != want {
+		t.Errorf("inject traceparent err: %s, want: %s", out.Get(TraceparentHeader), want)
+	}
+	if out.Get(TracestateHeader) != "congo=t61rcWkgMzE" {
+		t.Errorf("inject tracestate err: %s", out.Get(TracestateHeader))
+	}
+
+	// no trace context, nothing injected
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Errorf("inject without trace err: %v", empty)
+	}
+}
+
+type memExporter struct {
+	mu       sync.Mutex
+	spans    []*Span
+	shutdown bool
+}
+
+func (e *memExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *memExporter) Shutdown(ctx context.Context) error {
+	e.shutdown = true
+	return nil
+}
+
+func TestExporter(t *testing.T) {
+	exp := new(memExporter)
+	SetExporter(exp, WithMaxExportBatchSize(2))
+
+	ctx, root := StartSpan(context.Background(), "root", SpanKindServer)
+	_, child := StartSpan(ctx, "child", SpanKindClient)
+	child.SetError(errors.New("timeout"))
+	child.End()
+	child.End()
+	root.End()
+
+	// not sampled, never exported
+	unsampled := ContextWithRemoteSpanContext(context.Background(), SpanContext{TraceID: newTraceID(), SpanID: newSpanID()})
+	_, s := StartSpan(unsampled, "unsampled", SpanKindServer)
+	s.End()
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !exp.shutdown {
+		t.Error("exporter should be shutdown")
+	}
+
+	if len(exp.spans) != 2 {
+		t.Fatalf("exported spans: %d, want: 2", len(exp.spans))
+	}
+
+	if exp.spans[0].Name != "child" || exp.spans[0].StatusCode != StatusCodeError || exp.spans[0].ParentSpanID != root.SpanContext.SpanID {
+		t.Errorf("child span err: %+v", exp.spans[0])
+	}
+
+	if exp.spans[1].SpanContext.TraceID != exp.spans[0].SpanContext.TraceID {
+		t.Error("spans should be in the same trace")
+	}
+}
@@ -21,17 +21,21 @@ func Ins(trans ut.Translator) *validator.Validate {
 		return v
 	}
 
-	lang := trans.Locale()
-	switch lang {
+	RegisterTranslations(v, trans)
+
+	return v
+}
+
+// 在 validate 上注册 trans 语言的内置翻译和自定义校验, 多个语言需要分别注册
+func RegisterTranslations(validate *validator.Validate, trans ut.Translator) {
+	switch trans.Locale() {
 	case "zh":
-		_ = zh.RegisterDefaultTranslations(v, trans)
+		_ = zh.RegisterDefaultTranslations(validate, trans)
 	case "en":
-		_ = en.RegisterDefaultTranslations(v, trans)
+		_ = en.RegisterDefaultTranslations(validate, trans)
 	default:
-		_ = zh.RegisterDefaultTranslations(v, trans)
+		_ = zh.RegisterDefaultTranslations(validate, trans)
 	}
 
-	registerPhoneValidator(v)
-
-	return v
+	registerPhoneValidator(validate, trans)
 }
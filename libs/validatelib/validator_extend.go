@@ -5,10 +5,9 @@ import (
 
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
-	"github.com/hulklab/yago"
 )
 
-func registerPhoneValidator(v *validator.Validate) {
+func registerPhoneValidator(v *validator.Validate, trans ut.Translator) {
 	// 注册自定义验证器和翻译
 	// 例：添加一个手机号验证器
 	// 添加手机号验证器
@@ -19,7 +18,7 @@ func registerPhoneValidator(v *validator.Validate) {
 	})
 
 	// 添加手机号验证器的翻译
-	_ = v.RegisterTranslation("phone", yago.GetTranslator(), func(ut ut.Translator) error {
+	_ = v.RegisterTranslation("phone", trans, func(ut ut.Translator) error {
 		var e error
 		switch ut.Locale() {
 		case "zh":
@@ -0,0 +1,198 @@
+package yago
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// 内置指标与组件指标都注册到此 registry, 通过 app.metrics_route 以 prometheus 文本格式暴露
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "yago",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of http requests by route template.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "yago",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Http request latency by route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	rpcRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "yago",
+		Subsystem: "rpc",
+		Name:      "requests_total",
+		Help:      "Total number of rpc requests by method and status code.",
+	}, []string{"method", "code"})
+
+	rpcRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "yago",
+		Subsystem: "rpc",
+		Name:      "request_duration_seconds",
+		Help:      "Rpc request latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	taskExecutionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "yago",
+		Subsystem: "task",
+		Name:      "executions_total",
+		Help:      "Total number of task executions by result.",
+	}, []string{"task", "result"})
+
+	taskExecutionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "yago",
+		Subsystem: "task",
+		Name:      "execution_duration_seconds",
+		Help:      "Task execution latency.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 600},
+	}, []string{"task"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		rpcRequestsTotal,
+		rpcRequestDuration,
+		taskExecutionsTotal,
+		taskExecutionDuration,
+	)
+}
+
+// 是否开启了 app.metrics_on
+func MetricsEnabled() bool {
+	return Config.GetBool("app.metrics_on")
+}
+
+// 注册自定义指标, 重复注册时替换已注册的 collector
+// 组件被 Del 后重新创建时, 指标来自新的实例, 不会 panic 也不会继续上报已关闭的实例
+func RegisterMetrics(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		err := MetricsRegistry.Register(c)
+		if err == nil {
+			continue
+		}
+
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+
+		MetricsRegistry.Unregister(are.ExistingCollector)
+		if err := MetricsRegistry.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// 注销组件指标, 如组件关闭时
+func UnregisterMetrics(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		MetricsRegistry.Unregister(c)
+	}
+}
+
+func (a *App) loadMetricsRouter() {
+	if !MetricsEnabled() {
+		return
+	}
+
+	route := "/metrics"
+	if Config.IsSet("app.metrics_route") {
+		route = Config.GetString("app.metrics_route")
+	}
+
+	a.httpEngine.GET(route, gin.WrapH(promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{})))
+
+	debugf("[HTTP] %-6s %-25s --> %s\n", http.MethodGet, route, "Metrics")
+
+	// 在健康检查和指标路由之后注册, 不记录探测请求
+	a.httpEngine.Use(httpMetricsMiddleware)
+}
+
+// recovery 中间件在指标中间件之前, panic 时在 defer 中记录为 500, panic 继续向上抛出
+func httpMetricsMiddleware(c *gin.Context) {
+	begin := time.Now()
+	panicked := true
+
+	defer func() {
+		// 使用路由模板, 与 HttpRouter.Url() 一致, 避免路径参数导致 label 过多
+		route := c.FullPath()
+		if route == "" {
+			route = "NoRoute"
+		}
+
+		code := c.Writer.Status()
+		if panicked {
+			code = http.StatusInternalServerError
+		}
+
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(code)).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(begin).Seconds())
+	}()
+
+	c.Next()
+
+	panicked = false
+}
+
+func rpcMetricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	begin := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	observeRpc(info.FullMethod, begin, err)
+
+	return resp, err
+}
+
+func rpcMetricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	begin := time.Now()
+
+	err := handler(srv, ss)
+
+	observeRpc(info.FullMethod, begin, err)
+
+	return err
+}
+
+func observeRpc(method string, begin time.Time, err error) {
+	rpcRequestsTotal.WithLabelValues(method, status.Code(err).String()).Inc()
+	rpcRequestDuration.WithLabelValues(method).Observe(time.Since(begin).Seconds())
+}
+
+// panic 仍然向上抛出, 由 cron 处理
+func taskMetricsHandler(name string, action TaskHandlerFunc) TaskHandlerFunc {
+	return func() {
+		begin := time.Now()
+		result := "panic"
+
+		defer func() {
+			taskExecutionsTotal.WithLabelValues(name, result).Inc()
+			taskExecutionDuration.WithLabelValues(name).Observe(time.Since(begin).Seconds())
+		}()
+
+		action()
+
+		result = "ok"
+	}
+}
@@ -0,0 +1,85 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// go test -v . -test.run "TestRegisterMetrics|TestHttpMetrics"
+
+func TestRegisterMetrics(t *testing.T) {
+	gauge := func(v float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "yago",
+			Subsystem: "test",
+			Name:      "value",
+			Help:      "Test value.",
+		}, func() float64 { return v })
+	}
+
+	value := func() float64 {
+		mfs, err := MetricsRegistry.Gather()
+		if err != nil {
+			t.Fatal("gather err:", err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() == "yago_test_value" {
+				return mf.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		t.Fatal("yago_test_value is not registered")
+		return 0
+	}
+
+	RegisterMetrics(gauge(1))
+	if v := value(); v != 1 {
+		t.Errorf("value got %v, want 1", v)
+	}
+
+	// 组件重新创建时, 使用新实例的指标
+	latest := gauge(2)
+	RegisterMetrics(latest)
+	if v := value(); v != 2 {
+		t.Errorf("value after register again got %v, want 2", v)
+	}
+
+	UnregisterMetrics(latest)
+}
+
+// recovery 在指标中间件之前, panic 的请求也记录为 500
+func TestHttpMetrics_Panic(t *testing.T) {
+	logger := httpPanicLogger
+	defer func() {
+		httpPanicLogger = logger
+	}()
+	SetHttpPanicLogger(func(c *Ctx, p interface{}, stack []byte) {})
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(httpRecoveryMiddleware, httpMetricsMiddleware)
+	e.GET("/metrics-test/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	e.GET("/metrics-test/ok", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	panics := httpRequestsTotal.WithLabelValues(http.MethodGet, "/metrics-test/panic", "500")
+	oks := httpRequestsTotal.WithLabelValues(http.MethodGet, "/metrics-test/ok", "200")
+	before, beforeOk := testutil.ToFloat64(panics), testutil.ToFloat64(oks)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/panic", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/ok", nil))
+
+	if got := testutil.ToFloat64(panics) - before; got != 1 {
+		t.Errorf("panic requests got %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(oks) - beforeOk; got != 1 {
+		t.Errorf("ok requests got %v, want 1", got)
+	}
+}
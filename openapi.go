@@ -0,0 +1,633 @@
+package yago
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+)
+
+// 路由 metadata 实现以下接口时, 用于 openapi 文档
+// eg. func (m HttpMetadata) OpenAPISummary() string { return m.Label }
+type OpenAPISummarizer interface {
+	OpenAPISummary() string
+}
+
+type OpenAPIDescriber interface {
+	OpenAPIDescription() string
+}
+
+type OpenAPITagger interface {
+	OpenAPITags() []string
+}
+
+type OpenAPIDoc struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+	// 所有登记的错误码, 见 yago.RegisterErr
+	ErrCodes []errCodeDoc `json:"x-error-codes,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas"`
+}
+
+type OpenAPIOperation struct {
+	OperationId string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+}
+
+const openAPIResponseBody = "yago.ResponseBody"
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	bytesType     = reflect.TypeOf([]byte(nil))
+	anyMethods    = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	bodylessVerbs = map[string]bool{http.MethodGet: true, http.MethodHead: true, http.MethodDelete: true, http.MethodOptions: true}
+)
+
+type openAPIGenerator struct {
+	doc *OpenAPIDoc
+	// 结构体类型 => components 中的名称
+	names map[reflect.Type]string
+	// operationId 需要唯一, 同一个 handler 注册多个路由时加上序号
+	operationIds map[string]bool
+}
+
+// 根据注册的路由, typed handler 的请求响应类型和错误码生成 openapi 3 文档
+func GenOpenAPIDoc() *OpenAPIDoc {
+	title := Config.GetString("app.app_name")
+	if title == "" {
+		title = "app"
+	}
+
+	version := "1.0.0"
+	if Config.IsSet("app.openapi_version") {
+		version = Config.GetString("app.openapi_version")
+	}
+
+	g := &openAPIGenerator{
+		doc: &OpenAPIDoc{
+			OpenAPI: "3.0.3",
+			Info: OpenAPIInfo{
+				Title:       title,
+				Version:     version,
+				Description: Config.GetString("app.openapi_description"),
+			},
+			Paths: make(map[string]map[string]*OpenAPIOperation),
+			Components: OpenAPIComponents{
+				Schemas: make(map[string]*OpenAPISchema),
+			},
+			ErrCodes: getErrCodeDocs(),
+		},
+		names:        make(map[reflect.Type]string),
+		operationIds: make(map[string]bool),
+	}
+
+	g.doc.Components.Schemas[openAPIResponseBody] = &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"errno":   {Type: "integer", Description: "错误码, 0 为成功, 见 x-error-codes"},
+			"errmsg":  {Type: "string", Description: "错误信息"},
+			"data":    {Description: "业务数据"},
+			"details": {Type: "object", Description: "错误详情"},
+		},
+		Required: []string{"errno", "errmsg"},
+	}
+
+	routers := GetHttpRouters()
+	sort.SliceStable(routers, func(i, j int) bool {
+		if routers[i].Url() != routers[j].Url() {
+			return routers[i].Url() < routers[j].Url()
+		}
+		return routers[i].Method < routers[j].Method
+	})
+
+	for _, r := range routers {
+		methods := []string{strings.ToUpper(r.Method)}
+		if r.Method == "Any" {
+			methods = anyMethods
+		}
+
+		path := openAPIPath(r.Url())
+		for _, method := range methods {
+			if g.doc.Paths[path] == nil {
+				g.doc.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			g.doc.Paths[path][strings.ToLower(method)] = g.genOperation(r, method)
+		}
+	}
+
+	return g.doc
+}
+
+// /users/:id/*file => /users/{id}/{file}
+func openAPIPath(url string) string {
+	segments := strings.Split(url, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func openAPIPathParams(url string) []string {
+	params := make([]string, 0)
+	for _, s := range strings.Split(url, "/") {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			params = append(params, s[1:])
+		}
+	}
+	return params
+}
+
+func (g *openAPIGenerator) genOperation(r *HttpRouter, method string) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationId: g.uniqueOperationId(openAPIOperationId(r.handlerName)),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+
+	if v, ok := r.Metadata.(OpenAPISummarizer); ok {
+		op.Summary = v.OpenAPISummary()
+	}
+	if v, ok := r.Metadata.(OpenAPIDescriber); ok {
+		op.Description = v.OpenAPIDescription()
+	}
+	if v, ok := r.Metadata.(OpenAPITagger); ok {
+		op.Tags = v.OpenAPITags()
+	}
+
+	declared := make(map[string]bool)
+	if r.RequestType != nil {
+		g.genRequest(op, r.RequestType.Elem(), method, declared)
+	} else if !bodylessVerbs[method] {
+		// 普通 handler 的请求未知, 不能当作没有 body
+		op.RequestBody = &OpenAPIRequestBody{
+			Description: "未声明, 不是 typed handler",
+			Content: map[string]*OpenAPIMediaType{
+				"*/*": {Schema: &OpenAPISchema{}},
+			},
+		}
+	}
+
+	for _, name := range openAPIPathParams(r.Url()) {
+		if !declared["path."+name] {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: name, In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"},
+			})
+		}
+	}
+
+	body := &OpenAPISchema{Ref: "#/components/schemas/" + openAPIResponseBody}
+	if r.ResponseType != nil {
+		body = &OpenAPISchema{
+			Type: "object",
+			Properties: map[string]*OpenAPISchema{
+				"errno":  {Type: "integer"},
+				"errmsg": {Type: "string"},
+				"data":   g.schemaOf(r.ResponseType),
+			},
+			Required: []string{"errno", "errmsg"},
+		}
+	}
+
+	op.Responses["200"] = &OpenAPIResponse{
+		Description: "errno 为 0 时成功, 否则为错误",
+		Content: map[string]*OpenAPIMediaType{
+			"application/json": {Schema: body},
+		},
+	}
+
+	return op
+}
+
+func (g *openAPIGenerator) uniqueOperationId(id string) string {
+	if id == "" {
+		return ""
+	}
+
+	unique := id
+	for i := 2; g.operationIds[unique]; i++ {
+		unique = id + strconv.Itoa(i)
+	}
+	g.operationIds[unique] = true
+
+	return unique
+}
+
+// github.com/x/app/modules/demo/demohttp.(*UserHttp).ListAction-fm => UserHttp.ListAction
+func openAPIOperationId(handlerName string) string {
+	if i := strings.LastIndex(handlerName, "/"); i >= 0 {
+		handlerName = handlerName[i+1:]
+	}
+	if i := strings.Index(handlerName, "."); i >= 0 {
+		handlerName = handlerName[i+1:]
+	}
+	handlerName = strings.TrimSuffix(handlerName, "-fm")
+	return strings.NewReplacer("(", "", ")", "", "*", "").Replace(handlerName)
+}
+
+// uri, header 字段作为参数, get 请求的字段和只有 form tag 的字段作为 query, 其余字段作为 json body
+func (g *openAPIGenerator) genRequest(op *OpenAPIOperation, t reflect.Type, method string, declared map[string]bool) {
+	body := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+
+	for _, f := range openAPIFields(t) {
+		schema := g.fieldSchema(f)
+		required := isRequiredField(f)
+
+		if name := tagName(f, "uri"); name != "" {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: name, In: "path", Required: true, Description: schema.Description, Schema: schema,
+			})
+			declared["path."+name] = true
+			continue
+		}
+
+		if name := tagName(f, "header"); name != "" {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: name, In: "header", Required: required, Description: schema.Description, Schema: schema,
+			})
+			continue
+		}
+
+		if bodylessVerbs[method] || (tagName(f, "form") != "" && tagName(f, "json") == "") {
+			name := tagName(f, "form")
+			if name == "" {
+				name = f.Name
+			}
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: name, In: "query", Required: required, Description: schema.Description, Schema: schema,
+			})
+			continue
+		}
+
+		name := tagName(f, "json")
+		if name == "" {
+			name = f.Name
+		}
+		body.Properties[name] = schema
+		if required {
+			body.Required = append(body.Required, name)
+		}
+	}
+
+	if len(body.Properties) > 0 {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]*OpenAPIMediaType{
+				"application/json": {Schema: body},
+			},
+		}
+	}
+}
+
+// 导出字段, 匿名结构体字段展开
+func openAPIFields(t reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		if f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && tagName(f, "json") == "" {
+			fields = append(fields, openAPIFields(ft)...)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func tagName(f reflect.StructField, key string) string {
+	name := strings.SplitN(f.Tag.Get(key), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func validateRules(f reflect.StructField) []string {
+	rules := make([]string, 0)
+	for _, key := range []string{"validate", "binding"} {
+		if v := f.Tag.Get(key); v != "" {
+			rules = append(rules, strings.Split(v, ",")...)
+		}
+	}
+	return rules
+}
+
+func isRequiredField(f reflect.StructField) bool {
+	for _, rule := range validateRules(f) {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *openAPIGenerator) fieldSchema(f reflect.StructField) *OpenAPISchema {
+	schema := g.schemaOf(f.Type)
+
+	label := tagName(f, "label")
+	if schema.Ref != "" {
+		// $ref 不能与其他属性同时使用
+		return schema
+	}
+
+	copied := *schema
+	schema = &copied
+	schema.Description = label
+
+	for _, rule := range validateRules(f) {
+		kv := strings.SplitN(rule, "=", 2)
+		if len(kv) != 2 {
+			if kv[0] == "email" {
+				schema.Format = "email"
+			}
+			continue
+		}
+
+		switch kv[0] {
+		case "min", "gte":
+			setSchemaBound(schema, kv[1], true)
+		case "max", "lte":
+			setSchemaBound(schema, kv[1], false)
+		case "len":
+			setSchemaBound(schema, kv[1], true)
+			setSchemaBound(schema, kv[1], false)
+		case "oneof":
+			for _, v := range strings.Fields(kv[1]) {
+				schema.Enum = append(schema.Enum, v)
+			}
+		}
+	}
+
+	return schema
+}
+
+func setSchemaBound(schema *OpenAPISchema, value string, lower bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &i
+		} else {
+			schema.MaxLength = &i
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &i
+		} else {
+			schema.MaxItems = &i
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
+
+func (g *openAPIGenerator) schemaOf(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case t == bytesType:
+		return &OpenAPISchema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &OpenAPISchema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+
+	// interface{} 等任意类型
+	return &OpenAPISchema{}
+}
+
+// 具名结构体放在 components 中引用, 可以处理递归类型
+func (g *openAPIGenerator) structSchema(t reflect.Type) *OpenAPISchema {
+	if t.Name() == "" {
+		return g.genStructSchema(t)
+	}
+
+	if name, ok := g.names[t]; ok {
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := g.componentName(t)
+	g.names[t] = name
+
+	// 先占位, 递归引用时使用同一个名称
+	schema := new(OpenAPISchema)
+	g.doc.Components.Schemas[name] = schema
+	*schema = *g.genStructSchema(t)
+
+	return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *openAPIGenerator) componentName(t reflect.Type) string {
+	name := strings.NewReplacer("[", "_", "]", "", "*", "", "/", "_", " ", "").Replace(t.String())
+	if _, exist := g.doc.Components.Schemas[name]; !exist {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		n := name + strconv.Itoa(i)
+		if _, exist := g.doc.Components.Schemas[n]; !exist {
+			return n
+		}
+	}
+}
+
+func (g *openAPIGenerator) genStructSchema(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+
+	for _, f := range openAPIFields(t) {
+		name := tagName(f, "json")
+		if name == "" {
+			name = f.Name
+		}
+
+		schema.Properties[name] = g.fieldSchema(f)
+		if isRequiredField(f) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// 需要在 router init hook 执行之后调用, 否则缺少在 yago.AddRouterInitHook 中注册的路由, 如 rpc 网关
+func ExportOpenAPIDoc(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(GenOpenAPIDoc())
+}
+
+// 路由在 Run 之后不再变化, 文档只生成一次
+func (a *App) loadOpenAPIRouter() {
+	if !a.HttpOpenAPIOn {
+		return
+	}
+
+	route := "/openapi.json"
+	if Config.IsSet("app.http_openapi_route") {
+		route = Config.GetString("app.http_openapi_route")
+	}
+
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+
+	a.httpEngine.GET(route, func(c *gin.Context) {
+		once.Do(func() {
+			doc, err = json.Marshal(GenOpenAPIDoc())
+		})
+
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json; charset=utf-8", doc)
+	})
+
+	debugf("[HTTP] %-6s %-25s --> %s\n", http.MethodGet, route, "OpenAPI")
+}
+
+func init() {
+	// ./app openapi -o openapi.json
+	AddCmdRouter("openapi", "Export OpenAPI 3 document of http routers", openAPICmdAction, CmdStringArg{
+		Name: "output", Shorthand: "o", Value: "", Usage: "output file, default stdout",
+	})
+}
+
+func openAPICmdAction(cmd *cobra.Command, args []string) {
+	output, _ := cmd.Flags().GetString("output")
+
+	// rpc 网关等路由在 RouterInitHook 中注册, 只执行这类 hook, 不执行 AppInitHook, 不创建 app
+	LoadErrCodes()
+	runRouterInitHooks()
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fatalln("create output file err:", err.Error())
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := ExportOpenAPIDoc(w); err != nil {
+		fatalln("export openapi doc err:", err.Error())
+	}
+}
@@ -0,0 +1,87 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// go test -v . -test.run "TestGenOpenAPIDoc|TestLoadOpenAPIRouter"
+
+func TestGenOpenAPIDoc(t *testing.T) {
+	g := NewHttpGroupRouter("/openapi-test")
+	g.PostTyped("/typed/:id", echoTypedTest)
+	g.Post("/untyped", func(c *Ctx) {})
+	g.Get("/untyped", func(c *Ctx) {})
+
+	doc := GenOpenAPIDoc()
+
+	typed := doc.Paths["/openapi-test/typed/{id}"]["post"]
+	if typed == nil || typed.RequestBody == nil || typed.RequestBody.Content["application/json"] == nil {
+		t.Fatalf("typed route should have json body, got %+v", typed)
+	}
+
+	untyped := doc.Paths["/openapi-test/untyped"]["post"]
+	if untyped == nil || untyped.RequestBody == nil || untyped.RequestBody.Content["*/*"] == nil {
+		t.Fatalf("untyped route should have unspecified body, got %+v", untyped)
+	}
+
+	if get := doc.Paths["/openapi-test/untyped"]["get"]; get == nil || get.RequestBody != nil {
+		t.Errorf("untyped get route should not have body, got %+v", get)
+	}
+}
+
+// openapi 命令只执行 RouterInitHook, 不执行 AppInitHook
+func TestGenOpenAPIDoc_RouterInitHook(t *testing.T) {
+	appHookCalled := false
+	AddAppInitHook(func(app *App) error {
+		appHookCalled = true
+		NewHttpGroupRouter("/openapi-app-hook").Get("/ping", func(c *Ctx) {})
+		return nil
+	})
+	AddRouterInitHook(func() error {
+		NewHttpGroupRouter("/openapi-router-hook").Get("/ping", func(c *Ctx) {})
+		return nil
+	})
+
+	runRouterInitHooks()
+	doc := GenOpenAPIDoc()
+
+	if appHookCalled {
+		t.Error("app init hook should not be called")
+	}
+	if doc.Paths["/openapi-router-hook/ping"]["get"] == nil {
+		t.Error("route registered in router init hook should be exported")
+	}
+	if _, ok := doc.Paths["/openapi-app-hook/ping"]; ok {
+		t.Error("route registered in app init hook should not be exported")
+	}
+}
+
+// 只开启了 openapi 路由时也启动 http 服务
+func TestLoadOpenAPIRouter_OnlyOpenAPI(t *testing.T) {
+	groups := httpGroupRouterMap
+	httpGroupRouterMap = make(map[string]*HttpGroupRouter)
+	defer func() {
+		httpGroupRouterMap = groups
+	}()
+
+	gin.SetMode(gin.TestMode)
+	a := &App{httpEngine: gin.New(), HttpOpenAPIOn: true}
+	if err := a.loadHttpRouter(); err != nil {
+		t.Fatal("load http router err:", err)
+	}
+
+	w := httptest.NewRecorder()
+	a.httpEngine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("openapi route got %d", w.Code)
+	}
+
+	a = &App{httpEngine: gin.New()}
+	if err := a.loadHttpRouter(); err != errHttpRouteEmpty {
+		t.Errorf("empty router got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package yago
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	runtimedebug "runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hulklab/yago/libs/tracelib"
+)
+
+// http panic 处理, stack 为 panic 时的调用栈
+type HttpPanicHandler func(c *Ctx, p interface{}, stack []byte)
+
+var (
+	// base/basehttp 会替换为 coms/logger 记录
+	httpPanicLogger HttpPanicHandler = stdHttpPanicLogger
+	httpPanicHooks  []HttpPanicHandler
+)
+
+// 设置 panic 日志记录方式
+func SetHttpPanicLogger(h HttpPanicHandler) {
+	httpPanicLogger = h
+}
+
+// 添加 panic 钩子, 在日志记录之后按添加顺序执行, 可用于告警
+// eg. yago.AddHttpPanicHook(func(c *yago.Ctx, p interface{}, stack []byte) { alarm.Send(c.FullPath(), p) })
+func AddHttpPanicHook(h HttpPanicHandler) {
+	httpPanicHooks = append(httpPanicHooks, h)
+}
+
+func stdHttpPanicLogger(c *Ctx, p interface{}, stack []byte) {
+	log.Printf("[http panic] %s %s request_id: %s, panic: %v\n%s", c.Request.Method, c.FullPath(), c.GetRequestId(), p, stack)
+}
+
+// 请求 id, 优先使用 X-Request-Id header, 没有时使用 trace id
+func (c *Ctx) GetRequestId() string {
+	if id := c.GetHeader("X-Request-Id"); id != "" {
+		return id
+	}
+
+	if c.Request != nil {
+		return tracelib.TraceIDFromContext(c.Request.Context())
+	}
+
+	return ""
+}
+
+// 始终开启, 记录 panic 并返回 ErrSystem
+func httpRecoveryMiddleware(c *gin.Context) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+
+		stack := runtimedebug.Stack()
+
+		ctx, err := getCtxFromGin(c)
+		if err != nil {
+			ctx = &Ctx{Context: c}
+		}
+
+		httpPanicLogger(ctx, p, stack)
+
+		for _, h := range httpPanicHooks {
+			runHttpPanicHook(h, ctx, p, stack)
+		}
+
+		// 连接已断开时无法返回
+		if isBrokenPipe(p) {
+			c.Abort()
+			return
+		}
+
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+
+		ctx.AbortWithE(ErrSystem)
+	}()
+
+	c.Next()
+}
+
+// 钩子中的 panic 不影响响应
+func runHttpPanicHook(h HttpPanicHandler, c *Ctx, p interface{}, stack []byte) {
+	defer func() {
+		if e := recover(); e != nil {
+			log.Printf("[http panic] hook panic: %v", e)
+		}
+	}()
+
+	h(c, p, stack)
+}
+
+func isBrokenPipe(p interface{}) bool {
+	err, ok := p.(error)
+	if !ok {
+		return false
+	}
+
+	var ne *net.OpError
+	if !errors.As(err, &ne) {
+		return false
+	}
+
+	var se *os.SyscallError
+	if !errors.As(ne, &se) {
+		return false
+	}
+
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,83 @@
+package yago
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// go test -v . -test.run TestHttpRecovery
+
+func TestHttpRecovery(t *testing.T) {
+	logger, hooks := httpPanicLogger, httpPanicHooks
+	defer func() {
+		httpPanicLogger, httpPanicHooks = logger, hooks
+	}()
+
+	var logged []interface{}
+	SetHttpPanicLogger(func(c *Ctx, p interface{}, stack []byte) {
+		logged = append(logged, p)
+	})
+	httpPanicHooks = nil
+	// 钩子中的 panic 不影响响应
+	AddHttpPanicHook(func(c *Ctx, p interface{}, stack []byte) {
+		panic("hook panic")
+	})
+
+	brokenPipe := &net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(httpRecoveryMiddleware)
+	e.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	e.GET("/panic-err", func(c *gin.Context) {
+		panic(errors.New("boom"))
+	})
+	e.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	e.GET("/broken-pipe", func(c *gin.Context) {
+		panic(brokenPipe)
+	})
+	e.GET("/ok", func(c *gin.Context) {
+		(&Ctx{Context: c}).SetData("ok")
+	})
+
+	get := func(path string) string {
+		logged = nil
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Body.String()
+	}
+
+	want := `{"errno":7,"errmsg":"System error"}`
+	if body := get("/panic"); body != want || len(logged) != 1 {
+		t.Errorf("panic got %s, logged %d", body, len(logged))
+	}
+	if body := get("/panic-err"); body != want || len(logged) != 1 {
+		t.Errorf("panic err got %s, logged %d", body, len(logged))
+	}
+
+	// 已经写了响应时不再追加错误
+	if body := get("/written"); body != "partial" || len(logged) != 1 {
+		t.Errorf("written got %s, logged %d", body, len(logged))
+	}
+
+	// 连接已断开时不写响应
+	if body := get("/broken-pipe"); body != "" || len(logged) != 1 {
+		t.Errorf("broken pipe got %s, logged %d", body, len(logged))
+	}
+
+	if body := get("/ok"); body != `{"errno":0,"errmsg":"","data":"ok"}` || len(logged) != 0 {
+		t.Errorf("ok got %s, logged %d", body, len(logged))
+	}
+}
@@ -0,0 +1,283 @@
+package yago
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/ugorji/go/codec"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// 响应渲染, 根据请求的 Accept 协商选择, 协商失败或渲染出错时使用 json
+type Renderer interface {
+	// 协商使用的 media type, 如 application/problem+json
+	ContentType() string
+	// 渲染 SetData 和 SetError 的结果, 写入之前出错会使用 json 重新渲染
+	Render(c *Ctx, status int, resp *ResponseBody) error
+}
+
+var errRenderUnsupported = errors.New("render unsupported data")
+
+var builtinRenderers = map[string]Renderer{
+	"json":     JSONRenderer{},
+	"problem":  ProblemRenderer{},
+	"xml":      XMLRenderer{},
+	"msgpack":  MsgPackRenderer{},
+	"protobuf": ProtoBufRenderer{},
+}
+
+var (
+	// 第一个为默认的渲染
+	httpRenderers      = []Renderer{JSONRenderer{}}
+	httpRenderOffers   = []string{JSONRenderer{}.ContentType()}
+	httpErrStatusOn    bool
+	httpErrStatusTable = make(map[int]int)
+)
+
+// 添加渲染, 在 Run 之前调用, 同一个 content type 后添加的覆盖先添加的
+// eg. app.AddRenderer(yago.ProblemRenderer{})
+func (a *App) AddRenderer(rs ...Renderer) {
+	for _, r := range rs {
+		replaced := false
+		for i, v := range a.HttpRenderers {
+			if v.ContentType() == r.ContentType() {
+				a.HttpRenderers[i] = r
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			a.HttpRenderers = append(a.HttpRenderers, r)
+		}
+	}
+}
+
+// 设置 yago.Err 对应的 http status, 开启 app.http_err_status_on 后生效
+// eg. app.SetHttpErrStatus(yago.ErrParam, http.StatusBadRequest)
+func (a *App) SetHttpErrStatus(ye Err, status int) {
+	a.HttpErrStatus[ye.Code()] = status
+}
+
+func (a *App) initHttpRender() {
+	a.HttpRenderers = []Renderer{JSONRenderer{}}
+	for _, name := range Config.GetStringSlice("app.http_renderers") {
+		r, ok := builtinRenderers[name]
+		if !ok {
+			fatalf("unknown http renderer: %s\n", name)
+		}
+		a.AddRenderer(r)
+	}
+
+	a.HttpErrStatusOn = Config.GetBool("app.http_err_status_on")
+	a.HttpErrStatus = make(map[int]int)
+
+	// eg. http_err_status = { 2 = 400, 1001 = 404 }
+	for k, v := range Config.GetStringMap("app.http_err_status") {
+		code, err := strconv.Atoi(k)
+		if err != nil {
+			fatalf("http_err_status key %s is not errno\n", k)
+		}
+		status, ok := v.(int64)
+		if !ok {
+			fatalf("http_err_status value of %s is not http status\n", k)
+		}
+		a.HttpErrStatus[code] = int(status)
+	}
+}
+
+func (a *App) loadHttpRender() {
+	httpRenderers = a.HttpRenderers
+	httpRenderOffers = make([]string, 0, len(a.HttpRenderers))
+	for _, r := range a.HttpRenderers {
+		httpRenderOffers = append(httpRenderOffers, r.ContentType())
+	}
+
+	httpErrStatusOn = a.HttpErrStatusOn
+
+	// 登记错误码时指定的 http status, 被 SetHttpErrStatus 和 app.http_err_status 覆盖
+	httpErrStatusTable = make(map[int]int)
+	for _, ec := range GetErrCodes() {
+		if ec.HttpStatus != 0 {
+			httpErrStatusTable[ec.Code] = ec.HttpStatus
+		}
+	}
+	for code, status := range a.HttpErrStatus {
+		httpErrStatusTable[code] = status
+	}
+}
+
+// 未配置的错误码返回 200
+func getHttpErrStatus(code int) int {
+	if !httpErrStatusOn {
+		return http.StatusOK
+	}
+
+	if status, ok := httpErrStatusTable[code]; ok {
+		return status
+	}
+
+	return http.StatusOK
+}
+
+func (c *Ctx) render(status int, resp *ResponseBody) {
+	if err := c.negotiateRenderer().Render(c, status, resp); err != nil {
+		_ = JSONRenderer{}.Render(c, status, resp)
+	}
+}
+
+func (c *Ctx) negotiateRenderer() Renderer {
+	if len(httpRenderers) > 1 {
+		format := c.NegotiateFormat(httpRenderOffers...)
+		for _, r := range httpRenderers {
+			if r.ContentType() == format {
+				return r
+			}
+		}
+	} else if len(httpRenderers) == 1 {
+		return httpRenderers[0]
+	}
+
+	return JSONRenderer{}
+}
+
+// 根据请求的 Accept 协商出的响应格式, 如响应缓存按格式区分
+func (c *Ctx) RenderContentType() string {
+	return c.negotiateRenderer().ContentType()
+}
+
+type JSONRenderer struct{}
+
+func (JSONRenderer) ContentType() string {
+	return "application/json"
+}
+
+func (JSONRenderer) Render(c *Ctx, status int, resp *ResponseBody) error {
+	c.JSON(status, resp)
+	return nil
+}
+
+// RFC 7807, 错误时返回 problem details, 响应的 status 总是与 problem 中的一致, 成功时与 json 一致
+type ProblemRenderer struct{}
+
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	ErrNo    int    `json:"errno"`
+	// yago.DetailedErr 中的详情
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (ProblemRenderer) ContentType() string {
+	return "application/problem+json"
+}
+
+func (r ProblemRenderer) Render(c *Ctx, status int, resp *ResponseBody) error {
+	if resp.ErrNo == ok.Code() {
+		return JSONRenderer{}.Render(c, status, resp)
+	}
+
+	// RFC 7807 要求 status 与响应的 status 一致, 未开启 app.http_err_status_on 时也使用错误码登记的 status 响应
+	problemStatus := status
+	if problemStatus == http.StatusOK {
+		problemStatus = http.StatusInternalServerError
+		if ec, ok := GetErrCode(resp.ErrNo); ok && ec.HttpStatus != 0 {
+			problemStatus = ec.HttpStatus
+		}
+	}
+
+	b, err := json.Marshal(&ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(problemStatus),
+		Status:   problemStatus,
+		Detail:   resp.ErrMsg,
+		Instance: c.Request.URL.Path,
+		ErrNo:    resp.ErrNo,
+		Details:  resp.Details,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Data(problemStatus, r.ContentType(), b)
+	return nil
+}
+
+type XMLRenderer struct{}
+
+type xmlResponseBody struct {
+	XMLName xml.Name    `xml:"response"`
+	ErrNo   int         `xml:"errno"`
+	ErrMsg  string      `xml:"errmsg"`
+	Data    interface{} `xml:"data,omitempty"`
+}
+
+func (XMLRenderer) ContentType() string {
+	return "application/xml"
+}
+
+// map 类型的 data 不能转成 xml, 会使用 json
+func (r XMLRenderer) Render(c *Ctx, status int, resp *ResponseBody) error {
+	b, err := xml.Marshal(&xmlResponseBody{
+		ErrNo:  resp.ErrNo,
+		ErrMsg: resp.ErrMsg,
+		Data:   resp.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Data(status, r.ContentType()+"; charset=utf-8", append([]byte(xml.Header), b...))
+	return nil
+}
+
+type MsgPackRenderer struct{}
+
+func (MsgPackRenderer) ContentType() string {
+	return "application/msgpack"
+}
+
+func (r MsgPackRenderer) Render(c *Ctx, status int, resp *ResponseBody) error {
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, new(codec.MsgpackHandle)).Encode(resp); err != nil {
+		return err
+	}
+
+	c.Data(status, r.ContentType(), b)
+	return nil
+}
+
+// 成功时 data 必须是 proto.Message, 错误时返回 google.rpc.Status, code 为 errno
+type ProtoBufRenderer struct{}
+
+func (ProtoBufRenderer) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (r ProtoBufRenderer) Render(c *Ctx, status int, resp *ResponseBody) error {
+	var m proto.Message
+
+	if resp.ErrNo != ok.Code() {
+		m = &rpcstatus.Status{Code: int32(resp.ErrNo), Message: resp.ErrMsg}
+	} else if v, ok := resp.Data.(protov1.Message); ok {
+		m = protov1.MessageV2(v)
+	} else {
+		return errRenderUnsupported
+	}
+
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	c.Data(status, r.ContentType(), b)
+	return nil
+}
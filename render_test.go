@@ -0,0 +1,139 @@
+package yago
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// go test -v . -test.run TestRender
+
+func loadRenderTest(t *testing.T, errStatusOn bool, rs ...Renderer) {
+	renderers, offers, statusOn, statusTable := httpRenderers, httpRenderOffers, httpErrStatusOn, httpErrStatusTable
+	t.Cleanup(func() {
+		httpRenderers, httpRenderOffers, httpErrStatusOn, httpErrStatusTable = renderers, offers, statusOn, statusTable
+	})
+
+	LoadErrCodes()
+
+	a := &App{HttpRenderers: []Renderer{JSONRenderer{}}, HttpErrStatus: make(map[int]int)}
+	a.AddRenderer(rs...)
+	a.HttpErrStatusOn = errStatusOn
+	a.SetHttpErrStatus(ErrOperate, http.StatusConflict)
+	a.loadHttpRender()
+}
+
+func newRenderTestEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/ok", func(c *gin.Context) {
+		(&Ctx{Context: c}).SetData(map[string]string{"name": "tom"})
+	})
+	e.GET("/err/:name", func(c *gin.Context) {
+		switch c.Param("name") {
+		case "param":
+			(&Ctx{Context: c}).SetError(ErrParam)
+		case "operate":
+			(&Ctx{Context: c}).SetError(ErrOperate)
+		default:
+			(&Ctx{Context: c}).SetError(E)
+		}
+	})
+	return e
+}
+
+func renderTest(e *gin.Engine, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestRender_Negotiate(t *testing.T) {
+	loadRenderTest(t, true, ProblemRenderer{}, XMLRenderer{}, ProtoBufRenderer{})
+	e := newRenderTestEngine()
+
+	w := renderTest(e, "/ok", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("no accept should use first renderer, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = renderTest(e, "/ok", "text/html")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || !strings.Contains(w.Body.String(), `"errno":0`) {
+		t.Errorf("unknown accept should fall back to json, got %s %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	w = renderTest(e, "/ok", "application/problem+json")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || !strings.Contains(w.Body.String(), `"data":{"name":"tom"}`) {
+		t.Errorf("problem on success should be json, got %s %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	w = renderTest(e, "/err/param", "application/problem+json")
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" || !strings.Contains(w.Body.String(), `"status":400`) {
+		t.Errorf("problem on error got %d %s %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	w = renderTest(e, "/err/param", "application/xml")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "<errno>2</errno>") {
+		t.Errorf("xml got %d %s", w.Code, w.Body.String())
+	}
+
+	// 按 Accept 中的顺序选择
+	w = renderTest(e, "/err/param", "application/xml, application/json")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xml") {
+		t.Errorf("accept order got %s", w.Header().Get("Content-Type"))
+	}
+
+	// map 不能转成 xml, 使用 json
+	w = renderTest(e, "/ok", "application/xml")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || !strings.Contains(w.Body.String(), `"name":"tom"`) {
+		t.Errorf("xml unsupported data got %s %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// 成功时 data 不是 proto.Message, 使用 json
+	w = renderTest(e, "/ok", "application/x-protobuf")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || !strings.Contains(w.Body.String(), `"name":"tom"`) {
+		t.Errorf("protobuf unsupported data got %s %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// SetHttpErrStatus 覆盖登记时的 status
+	w = renderTest(e, "/err/operate", "")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"errno":8`) {
+		t.Errorf("app err status got %d %s", w.Code, w.Body.String())
+	}
+
+	// 登记时没有 http status 的错误码返回 200
+	w = renderTest(e, "/err/e", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"errno":1`) {
+		t.Errorf("unmapped err got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// 未开启 app.http_err_status_on 时除 problem 外错误都返回 200
+func TestRender_ErrStatusOff(t *testing.T) {
+	loadRenderTest(t, false, ProblemRenderer{})
+	e := newRenderTestEngine()
+
+	w := renderTest(e, "/err/param", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"errno":2`) {
+		t.Errorf("json got %d %s", w.Code, w.Body.String())
+	}
+
+	// problem 使用错误码登记的 http status, 响应的 status 与 problem 中的一致
+	w = renderTest(e, "/err/param", "application/problem+json")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"title":"Bad Request","status":400`) {
+		t.Errorf("problem got %d %s", w.Code, w.Body.String())
+	}
+
+	// 没有登记 http status 的错误码使用 500
+	w = renderTest(e, "/err/e", "application/problem+json")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"title":"Internal Server Error","status":500`) {
+		t.Errorf("problem without http status got %d %s", w.Code, w.Body.String())
+	}
+}
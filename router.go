@@ -3,11 +3,14 @@ package yago
 import (
 	"log"
 	"net/http"
+	"reflect"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 )
 
 // http
@@ -19,6 +22,11 @@ type HttpRouter struct {
 	Method   string
 	Actions  []HttpHandlerFunc
 	Metadata interface{}
+	// typed handler 的请求和响应类型, 普通 handler 为 nil
+	RequestType  reflect.Type
+	ResponseType reflect.Type
+	// 最后一个 action 的函数名
+	handlerName string
 }
 
 func (h *HttpRouter) WithMetadata(md interface{}) *HttpRouter {
@@ -41,11 +49,34 @@ func (r *HttpGlobalMiddleware) Use(middleware ...HttpHandlerFunc) {
 }
 
 var (
-	httpGlobalMiddleware HttpGlobalMiddleware
-	httpGroupRouterMap   = make(map[string]*HttpGroupRouter)
-	httpNoRouterHandler  HttpHandlerFunc
+	// middlewares provided by yago base packages, run before global middlewares
+	httpDefaultMiddleware HttpGlobalMiddleware
+	httpGlobalMiddleware  HttpGlobalMiddleware
+	httpGroupRouterMap    = make(map[string]*HttpGroupRouter)
+	httpNoRouterHandler   HttpHandlerFunc
+	// key is method and route template like "GET /users/:id", built when http server loads routers
+	httpRouterIndex = make(map[string]*HttpRouter)
 )
 
+func HttpDefaultMiddleware() *HttpGlobalMiddleware {
+	return &httpDefaultMiddleware
+}
+
+// 按请求方法和路由模板查找路由, 路由模板与 gin.Context.FullPath() 一致
+func GetHttpRouter(method, fullPath string) (*HttpRouter, bool) {
+	if r, ok := httpRouterIndex[method+" "+fullPath]; ok {
+		return r, true
+	}
+
+	r, ok := httpRouterIndex["Any "+fullPath]
+	return r, ok
+}
+
+// 当前请求匹配的路由, 可以在中间件中读取路由的 Metadata
+func (c *Ctx) GetHttpRouter() (*HttpRouter, bool) {
+	return GetHttpRouter(c.Request.Method, c.FullPath())
+}
+
 func (h *HttpRouter) Url() string {
 	url := h.Path
 	p := h.Group
@@ -133,6 +164,10 @@ func (g *HttpGroupRouter) Use(middlewares ...HttpHandlerFunc) {
 }
 
 func (g *HttpGroupRouter) addHttpRouter(url, method string, actions ...HttpHandlerFunc) *HttpRouter {
+	if len(actions) == 0 {
+		log.Panicf("http router has no action: %s %s", method, url)
+	}
+
 	router := &HttpRouter{
 		Path:    url,
 		Method:  method,
@@ -140,11 +175,30 @@ func (g *HttpGroupRouter) addHttpRouter(url, method string, actions ...HttpHandl
 		Actions: actions,
 	}
 
+	last := actions[len(actions)-1]
+	router.handlerName = runtime.FuncForPC(reflect.ValueOf(last).Pointer()).Name()
+
 	g.HttpRouterList = append(g.HttpRouterList, router)
 
 	return router
 }
 
+// middleware 在 action 之前执行, action 的格式见 yago.Typed, 路由记录 action 的请求和响应类型
+func (g *HttpGroupRouter) addTypedHttpRouter(url, method string, action interface{}, middleware []HttpHandlerFunc) *HttpRouter {
+	th := newTypedHandler(action)
+
+	actions := make([]HttpHandlerFunc, 0, len(middleware)+1)
+	actions = append(actions, middleware...)
+	actions = append(actions, th.handle)
+
+	router := g.addHttpRouter(url, method, actions...)
+	router.RequestType = th.reqType
+	router.ResponseType = th.respType
+	router.handlerName = th.name
+
+	return router
+}
+
 func (g *HttpGroupRouter) Get(url string, actions ...HttpHandlerFunc) *HttpRouter {
 	return g.addHttpRouter(url, http.MethodGet, actions...)
 }
@@ -177,6 +231,105 @@ func (g *HttpGroupRouter) Any(url string, actions ...HttpHandlerFunc) *HttpRoute
 	return g.addHttpRouter(url, "Any", actions...)
 }
 
+// typed handler 路由
+// eg. ghttp.Root.PostTyped("/demo/user/list", h.ListAction, auth.Ins().Middleware())
+func (g *HttpGroupRouter) GetTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodGet, action, middleware)
+}
+
+func (g *HttpGroupRouter) PostTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodPost, action, middleware)
+}
+
+func (g *HttpGroupRouter) PutTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodPut, action, middleware)
+}
+
+func (g *HttpGroupRouter) DeleteTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodDelete, action, middleware)
+}
+
+func (g *HttpGroupRouter) PatchTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodPatch, action, middleware)
+}
+
+func (g *HttpGroupRouter) HeadTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodHead, action, middleware)
+}
+
+func (g *HttpGroupRouter) OptionsTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, http.MethodOptions, action, middleware)
+}
+
+func (g *HttpGroupRouter) AnyTyped(url string, action interface{}, middleware ...HttpHandlerFunc) *HttpRouter {
+	return g.addTypedHttpRouter(url, "Any", action, middleware)
+}
+
+// rpc
+type RpcInterceptors struct {
+	Unary  []grpc.UnaryServerInterceptor
+	Stream []grpc.StreamServerInterceptor
+}
+
+func (r *RpcInterceptors) UseUnary(interceptors ...grpc.UnaryServerInterceptor) {
+	r.Unary = append(r.Unary, interceptors...)
+}
+
+func (r *RpcInterceptors) UseStream(interceptors ...grpc.StreamServerInterceptor) {
+	r.Stream = append(r.Stream, interceptors...)
+}
+
+var (
+	// interceptors provided by yago base packages, run before global interceptors
+	rpcDefaultInterceptors = new(RpcInterceptors)
+	rpcGlobalInterceptors  = new(RpcInterceptors)
+	// key is service name like "app.demopb.Home" or full method like "/app.demopb.Home/Hello"
+	rpcScopedInterceptors = make(map[string]*RpcInterceptors)
+)
+
+func RpcDefaultInterceptors() *RpcInterceptors {
+	return rpcDefaultInterceptors
+}
+
+// global unary interceptors, run before service and method interceptors
+func RpcUseUnary(interceptors ...grpc.UnaryServerInterceptor) {
+	rpcGlobalInterceptors.UseUnary(interceptors...)
+}
+
+// global stream interceptors, run before service and method interceptors
+func RpcUseStream(interceptors ...grpc.StreamServerInterceptor) {
+	rpcGlobalInterceptors.UseStream(interceptors...)
+}
+
+// interceptors of one service, eg. yago.RpcService("app.demopb.Home").UseUnary(auth)
+func RpcService(service string) *RpcInterceptors {
+	if len(service) == 0 {
+		log.Panic("rpc service name can not be empty")
+	}
+
+	return getRpcScopedInterceptors(strings.TrimPrefix(service, "/"))
+}
+
+// interceptors of one method, eg. yago.RpcMethod("/app.demopb.Home/Hello").UseUnary(auth)
+func RpcMethod(fullMethod string) *RpcInterceptors {
+	if strings.Count(strings.Trim(fullMethod, "/"), "/") != 1 {
+		log.Panicf("rpc method name must be like /package.Service/Method: %s", fullMethod)
+	}
+
+	return getRpcScopedInterceptors("/" + strings.TrimPrefix(fullMethod, "/"))
+}
+
+func getRpcScopedInterceptors(key string) *RpcInterceptors {
+	if r, ok := rpcScopedInterceptors[key]; ok {
+		return r
+	}
+
+	r := new(RpcInterceptors)
+	rpcScopedInterceptors[key] = r
+
+	return r
+}
+
 // task
 type TaskHandlerFunc func()
 
@@ -0,0 +1,187 @@
+package yago
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+type rpcService struct {
+	desc *grpc.ServiceDesc
+	impl interface{}
+}
+
+// 收集在 init 中注册的 rpc 服务, grpc server 在 app 运行时才根据配置创建
+// 需要使用 protoc-gen-go-grpc 生成代码, RegisterXxxServer 接收 grpc.ServiceRegistrar
+// eg. pb.RegisterHomeServer(yago.RpcRegistry, new(HomeRpc))
+type RpcRegistrar struct {
+	services []rpcService
+	server   *grpc.Server
+}
+
+var RpcRegistry = new(RpcRegistrar)
+
+// Deprecated: 兼容 protoc-gen-go plugins=grpc 生成的 RegisterXxxServer(s *grpc.Server, ...), 请改用 RpcRegistry
+// 在包 init 时按配置文件创建, 有服务注册到这里时 app 使用它提供服务, AddAppInitHook 中修改的 rpc_* 配置不生效,
+// 注册到这里的服务也不能通过 RpcRegistry.GetService 获取, 不能挂载 http 网关
+var RpcServer *grpc.Server
+
+func (r *RpcRegistrar) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	r.services = append(r.services, rpcService{desc: desc, impl: impl})
+
+	if r.server != nil {
+		r.server.RegisterService(desc, impl)
+	}
+}
+
+// 包括注册到 RpcServer 的服务
+func (r *RpcRegistrar) GetServiceInfo() map[string]grpc.ServiceInfo {
+	if r.server != nil {
+		return r.server.GetServiceInfo()
+	}
+
+	info := RpcServer.GetServiceInfo()
+	for _, s := range r.services {
+		methods := make([]grpc.MethodInfo, 0, len(s.desc.Methods)+len(s.desc.Streams))
+		for _, m := range s.desc.Methods {
+			methods = append(methods, grpc.MethodInfo{Name: m.MethodName})
+		}
+		for _, m := range s.desc.Streams {
+			methods = append(methods, grpc.MethodInfo{
+				Name:           m.StreamName,
+				IsClientStream: m.ClientStreams,
+				IsServerStream: m.ServerStreams,
+			})
+		}
+		info[s.desc.ServiceName] = grpc.ServiceInfo{Methods: methods, Metadata: s.desc.Metadata}
+	}
+	return info
+}
+
+// 只能获取注册到 RpcRegistry 的服务
+func (r *RpcRegistrar) GetService(name string) (desc *grpc.ServiceDesc, impl interface{}, ok bool) {
+	for _, s := range r.services {
+		if s.desc.ServiceName == name {
+			return s.desc, s.impl, true
+		}
+	}
+	return nil, nil, false
+}
+
+// interceptor chain of rpc server, can be used to call service handler out of grpc server
+func (r *RpcRegistrar) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return rpcUnaryDispatcher
+}
+
+// grpc server, nil before app runs
+func (r *RpcRegistrar) Server() *grpc.Server {
+	return r.server
+}
+
+func (r *RpcRegistrar) serve() *grpc.Server {
+	server := RpcServer
+	if len(RpcServer.GetServiceInfo()) == 0 {
+		// server options are read from config after app init hooks
+		server = newGrpcServer()
+	}
+
+	for _, s := range r.services {
+		server.RegisterService(s.desc, s.impl)
+	}
+	r.server = server
+
+	return server
+}
+
+func newGrpcServer() *grpc.Server {
+	serverOptions := []grpc.ServerOption{
+		grpc.UnaryInterceptor(rpcUnaryDispatcher),
+		grpc.StreamInterceptor(rpcStreamDispatcher),
+	}
+
+	isSslOn := Config.GetBool("app.rpc_ssl_on")
+	if isSslOn {
+		// 实例化 grpc Server, 并开启 TSL 认证
+		serverOptions = append(serverOptions, grpc.Creds(newRpcServerCreds()))
+	}
+
+	if Config.IsSet("app.rpc_max_recv_msgsize_mb") {
+		serverOptions = append(serverOptions, grpc.MaxRecvMsgSize(Config.GetInt("app.rpc_max_recv_msgsize_mb")*1024*1024))
+	}
+
+	if Config.IsSet("app.rpc_max_send_msgsize_mb") {
+		serverOptions = append(serverOptions, grpc.MaxSendMsgSize(Config.GetInt("app.rpc_max_send_msgsize_mb")*1024*1024))
+	}
+
+	if Config.IsSet("app.rpc_max_concurrent_streams") {
+		serverOptions = append(serverOptions, grpc.MaxConcurrentStreams(Config.GetUint32("app.rpc_max_concurrent_streams")))
+	}
+
+	if Config.IsSet("app.rpc_connection_timeout") {
+		serverOptions = append(serverOptions, grpc.ConnectionTimeout(Config.GetDuration("app.rpc_connection_timeout")))
+	}
+
+	// 未设置的参数使用 grpc 默认值
+	kp := keepalive.ServerParameters{
+		MaxConnectionIdle:     Config.GetDuration("app.rpc_keepalive_max_connection_idle"),
+		MaxConnectionAge:      Config.GetDuration("app.rpc_keepalive_max_connection_age"),
+		MaxConnectionAgeGrace: Config.GetDuration("app.rpc_keepalive_max_connection_age_grace"),
+		Time:                  Config.GetDuration("app.rpc_keepalive_time"),
+		Timeout:               Config.GetDuration("app.rpc_keepalive_timeout"),
+	}
+	if kp != (keepalive.ServerParameters{}) {
+		serverOptions = append(serverOptions, grpc.KeepaliveParams(kp))
+	}
+
+	if Config.IsSet("app.rpc_keepalive_min_time") || Config.IsSet("app.rpc_keepalive_permit_without_stream") {
+		serverOptions = append(serverOptions, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             Config.GetDuration("app.rpc_keepalive_min_time"),
+			PermitWithoutStream: Config.GetBool("app.rpc_keepalive_permit_without_stream"),
+		}))
+	}
+
+	return grpc.NewServer(serverOptions...)
+}
+
+func newRpcServerCreds() credentials.TransportCredentials {
+	certFile := Config.GetString("app.rpc_cert_file")
+	keyFile := Config.GetString("app.rpc_key_file")
+	if certFile == "" || keyFile == "" {
+		fatalln("rpc ssl cert file or key file is required when rpc ssl on")
+	}
+
+	// 未配置 client ca 时只做单向认证
+	clientCAFile := Config.GetString("app.rpc_client_ca_file")
+	if clientCAFile == "" {
+		cred, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			fatalf("Failed to generate credentials %v", err)
+		}
+		return cred
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		fatalf("Failed to load rpc cert file %v", err)
+	}
+
+	pem, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		fatalf("Failed to read rpc client ca file %v", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		fatalln("Failed to load rpc client ca file", clientCAFile)
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+}
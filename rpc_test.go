@@ -0,0 +1,69 @@
+package yago
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// go test -v . -test.run TestRpcInterceptorChain
+
+func TestRpcInterceptorChain(t *testing.T) {
+	defaults, globals, scoped := rpcDefaultInterceptors, rpcGlobalInterceptors, rpcScopedInterceptors
+	defer func() {
+		rpcDefaultInterceptors, rpcGlobalInterceptors, rpcScopedInterceptors = defaults, globals, scoped
+	}()
+	rpcDefaultInterceptors = new(RpcInterceptors)
+	rpcGlobalInterceptors = new(RpcInterceptors)
+	rpcScopedInterceptors = make(map[string]*RpcInterceptors)
+
+	RpcRegistry.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Chain",
+		Methods:     []grpc.MethodDesc{{MethodName: "Hello"}, {MethodName: "Bye"}},
+	}, nil)
+
+	var calls []string
+	mark := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	call := func(method string) []string {
+		calls = nil
+		_, err := RpcRegistry.UnaryInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls = append(calls, "handler")
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("%s err: %s", method, err)
+		}
+		return calls
+	}
+
+	RpcMethod("/test.Chain/Hello").UseUnary(mark("method"))
+	RpcService("test.Chain").UseUnary(mark("service"))
+	RpcUseUnary(mark("global"))
+	RpcDefaultInterceptors().UseUnary(mark("default"))
+
+	(&App{}).loadRpcInterceptor()
+
+	// 默认 -> 全局 -> 服务 -> 方法, 与添加的顺序无关
+	want := []string{"default", "global", "service", "method", "handler"}
+	if got := call("/test.Chain/Hello"); !reflect.DeepEqual(got, want) {
+		t.Errorf("method calls got %v, want %v", got, want)
+	}
+
+	want = []string{"default", "global", "service", "handler"}
+	if got := call("/test.Chain/Bye"); !reflect.DeepEqual(got, want) {
+		t.Errorf("service calls got %v, want %v", got, want)
+	}
+
+	// 启动后注册的方法使用全局的拦截器
+	want = []string{"default", "global", "handler"}
+	if got := call("/test.Other/Hello"); !reflect.DeepEqual(got, want) {
+		t.Errorf("unregistered method calls got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package yago
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// 自定义服务, 如 websocket 网关, tcp 服务, kafka 消费者等, 与内置的 http, rpc, task 服务一起启动和关闭
+type Server interface {
+	// Start 在服务准备好后返回, 耗时的处理放到 goroutine 中, ctx 在 app 开始关闭时 cancel
+	Start(ctx context.Context) error
+	// Stop 优雅关闭服务, ctx 在超过关闭等待时长后 done
+	Stop(ctx context.Context) error
+}
+
+type serverEntry struct {
+	name        string
+	server      Server
+	stopTimeout time.Duration
+}
+
+type ServerOption func(e *serverEntry)
+
+// 服务关闭最大等待时长, 默认 10s
+func WithStopTimeout(d time.Duration) ServerOption {
+	return func(e *serverEntry) {
+		e.stopTimeout = d
+	}
+}
+
+func newServerEntry(name string, s Server, opts ...ServerOption) *serverEntry {
+	e := &serverEntry{
+		name:        name,
+		server:      s,
+		stopTimeout: 10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// 在 Run 之前添加, 可以在 AddAppInitHook 中调用, 按添加顺序在内置服务之后启动, 按逆序关闭
+// eg. app.AddServer("Websocket", ws, yago.WithStopTimeout(5*time.Second))
+func (a *App) AddServer(name string, s Server, opts ...ServerOption) {
+	a.servers = append(a.servers, newServerEntry(name, s, opts...))
+}
+
+func (a *App) startServers() {
+	for _, e := range a.servers {
+		if err := e.server.Start(a.ctx); err != nil {
+			fatalf("%s server start err: %s\n", e.name, err)
+		}
+	}
+}
+
+func (a *App) stopServers() {
+	for i := len(a.servers) - 1; i >= 0; i-- {
+		e := a.servers[i]
+
+		ctx, cancel := context.WithTimeout(context.Background(), e.stopTimeout)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- e.server.Stop(ctx)
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Printf("%s Server Stop Error: %s\n", e.name, err)
+			} else {
+				log.Printf("%s Server Stop OK\n", e.name)
+			}
+		case <-ctx.Done():
+			log.Printf("%s Server Stop Timeout\n", e.name)
+		}
+
+		cancel()
+	}
+}
+
+// 内置服务
+type builtinServer struct {
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+func (s *builtinServer) Start(ctx context.Context) error {
+	return s.start(ctx)
+}
+
+func (s *builtinServer) Stop(ctx context.Context) error {
+	return s.stop(ctx)
+}
+
+// 服务关闭最大等待时长, 秒, 未配置时为 10s
+func getStopTimeWait(key string) time.Duration {
+	if !Config.IsSet(key) {
+		return 10 * time.Second
+	}
+	return time.Duration(Config.GetInt64(key)) * time.Second
+}
+
+// 开始关闭到关闭服务之间的等待时长, 未配置时不等待
+func getReadyDrainWait() time.Duration {
+	return Config.GetDuration("app.ready_drain_wait")
+}
@@ -0,0 +1,33 @@
+package yago
+
+import (
+	"strconv"
+	"time"
+)
+
+// 客户端期望的超时时间, 只能缩短路由配置的超时, HttpThird 会把 ctx 的剩余时间通过它传给下游
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// 解析 X-Request-Timeout, 支持毫秒数或 time.Duration 格式
+// eg. "1500", "1.5s"
+func ParseRequestTimeout(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, ms > 0
+	}
+
+	d, err := time.ParseDuration(v)
+	return d, err == nil && d > 0
+}
+
+// 格式化为 X-Request-Timeout 的毫秒数, 不足 1ms 时为 1
+func FormatRequestTimeout(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10)
+}
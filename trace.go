@@ -0,0 +1,112 @@
+package yago
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/hulklab/yago/libs/tracelib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 是否开启了 app.trace_on
+func TraceEnabled() bool {
+	return Config.GetBool("app.trace_on")
+}
+
+// grpc metadata 作为 tracelib.Carrier, key 为小写
+type RpcMetadataCarrier metadata.MD
+
+func (c RpcMetadataCarrier) Get(key string) string {
+	vs := metadata.MD(c).Get(key)
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[0]
+}
+
+func (c RpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (a *App) loadTraceMiddleware() {
+	if !TraceEnabled() {
+		return
+	}
+
+	// yago.Ctx falls back to request context, then it can be passed to orm, rds and third as context
+	a.httpEngine.ContextWithFallback = true
+
+	a.httpEngine.Use(httpTraceMiddleware)
+}
+
+func httpTraceMiddleware(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = "NoRoute"
+	}
+
+	ctx := tracelib.Extract(c.Request.Context(), c.Request.Header)
+	ctx, span := tracelib.StartSpan(ctx, c.Request.Method+" "+route, tracelib.SpanKindServer)
+	defer span.End()
+
+	span.SetAttribute("http.method", c.Request.Method)
+	span.SetAttribute("http.route", route)
+	span.SetAttribute("http.target", c.Request.URL.RequestURI())
+	span.SetAttribute("net.peer.ip", c.ClientIP())
+
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	code := c.Writer.Status()
+	span.SetAttribute("http.status_code", code)
+	if code >= 500 {
+		span.SetStatus(tracelib.StatusCodeError, strconv.Itoa(code))
+	}
+}
+
+func startRpcSpan(ctx context.Context, method string) (context.Context, *tracelib.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = tracelib.Extract(ctx, RpcMetadataCarrier(md))
+	}
+
+	ctx, span := tracelib.StartSpan(ctx, method, tracelib.SpanKindServer)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+
+	return ctx, span
+}
+
+func endRpcSpan(span *tracelib.Span, err error) {
+	code := status.Code(err)
+	span.SetAttribute("rpc.grpc.status_code", int(code))
+	span.SetError(err)
+	span.End()
+}
+
+func rpcTraceUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startRpcSpan(ctx, info.FullMethod)
+
+	resp, err := handler(ctx, req)
+
+	endRpcSpan(span, err)
+
+	return resp, err
+}
+
+func rpcTraceStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startRpcSpan(ss.Context(), info.FullMethod)
+
+	wrapped := grpcMiddleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+
+	err := handler(srv, wrapped)
+
+	endRpcSpan(span, err)
+
+	return err
+}
@@ -8,6 +8,9 @@ import (
 
 var uni *ut.UniversalTranslator
 
+// validator 内置翻译支持的语言
+var translatorLangs = []string{"zh", "en"}
+
 func init() {
 	cn := zh.New()
 	eng := en.New()
@@ -37,3 +40,12 @@ func GetTranslator(lang ...string) ut.Translator {
 	trans, _ := uni.GetTranslator(l)
 	return trans
 }
+
+// 所有语言的 translator, 用于注册 validator 翻译
+func GetTranslators() []ut.Translator {
+	list := make([]ut.Translator, 0, len(translatorLangs))
+	for _, l := range translatorLangs {
+		list = append(list, GetTranslator(l))
+	}
+	return list
+}
@@ -39,16 +39,18 @@ import (
 	"log"
 
 	"github.com/hulklab/yago"
+	"github.com/hulklab/yago/base/baserpc"
 
 	pb "github.com/hulklab/yago/example/app/modules/home/homerpc/homepb"
 )
 
 type {{.Name}}Rpc struct {
+	baserpc.BaseRpc
 }
 
 func init() {
 	h := new({{.Name}}Rpc)
-	pb.RegisterHomeServer(yago.RpcServer, h)
+	pb.RegisterHomeServer(yago.RpcRegistry, h)
 }
 
 func (r *{{.Name}}Rpc) Hello(ctx context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {